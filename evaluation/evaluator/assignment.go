@@ -0,0 +1,110 @@
+package evaluator
+
+import (
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/ast"
+)
+
+/*
+Evaluates an assignment to a name or an index, returning the value that was stored
+A compound assignment reads the current value before evaluating the new one, then combines them with its operator
+*/
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		return evalIdentifierAssignment(node, target, env)
+	case *ast.IndexExpression:
+		return evalIndexAssignment(node, target, env)
+	default:
+		return newError(node.Target, "cannot assign to %s", node.Target)
+	}
+}
+
+/*
+Changes the binding the name refers to, wherever it was made
+The name has to be bound already, an assignment never creates a binding the way let does
+*/
+func evalIdentifierAssignment(node *ast.AssignExpression, target *ast.Identifier, env *object.Environment) object.Object {
+	var current object.Object
+	if node.Operator() != "" {
+		current = evalIdentifier(target, env)
+		if isError(current) {
+			return current
+		}
+	}
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	value = applyAssignOperator(node, current, value)
+	if isError(value) {
+		return value
+	}
+
+	found, constant := env.Assign(target.Value, value)
+	switch {
+	case constant:
+		return newError(target, "cannot assign to %s, it is a constant", target.Value)
+	case !found:
+		return newError(target, "identifier not found: %s", target.Value)
+	}
+	return value
+}
+
+/*
+Stores the value in a hash under the index, or in an array at the index
+The collection and index are evaluated before the value, and an array index has to already be in range
+*/
+func evalIndexAssignment(node *ast.AssignExpression, target *ast.IndexExpression, env *object.Environment) object.Object {
+	left := Eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+	index := Eval(target.Index, env)
+	if isError(index) {
+		return index
+	}
+	var current object.Object
+	if node.Operator() != "" {
+		current = evalIndexExpression(target, left, index)
+		if isError(current) {
+			return current
+		}
+	}
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+	value = applyAssignOperator(node, current, value)
+	if isError(value) {
+		return value
+	}
+
+	switch left := left.(type) {
+	case *object.Hash:
+		key, err := hashKey(target.Index, index)
+		if err != nil {
+			return err
+		}
+		left.Set(key, value)
+		return value
+	case *object.Array:
+		i, err := resolveIndex(target.Index, index, len(left.Elements))
+		if err != nil {
+			return err
+		}
+		left.Elements[i] = value
+		return value
+	default:
+		return newError(target, "index assignment not supported: %s", left.Type())
+	}
+}
+
+// Combines the current value with the new one for a compound assignment, so x += 1 works out the same as x + 1
+func applyAssignOperator(node *ast.AssignExpression, current, value object.Object) object.Object {
+	if node.Operator() == "" {
+		return value
+	}
+	infix := &ast.InfixExpression{Token: node.Token, Left: node.Target, Operator: node.Operator(), Right: node.Value, Loc: node.Loc}
+	return evalInfixExpression(infix, current, value)
+}
@@ -0,0 +1,203 @@
+package evaluator
+
+import (
+	"unicode/utf8"
+
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/ast"
+)
+
+/*
+Functions built into Clear
+They are found after every binding in scope, so a program can still use their names for its own bindings
+*/
+var builtins = map[string]*object.Builtin{
+	"len": {Name: "len", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if err := checkArgumentCount(call, args, 1); err != nil {
+			return err
+		}
+		switch arg := args[0].(type) {
+		case *object.Array:
+			return &object.Integer{Value: int64(len(arg.Elements))}
+		case *object.String:
+			return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+		case *object.Hash:
+			return &object.Integer{Value: int64(arg.Len())}
+		case *object.Range:
+			return &object.Integer{Value: arg.Len()}
+		default:
+			return newError(call.Arguments[0], "argument to `len` not supported, got %s", args[0].Type())
+		}
+	}},
+	// Returns a new array with the value added to the end, the array itself is left as it was
+	"push": {Name: "push", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if err := checkArgumentCount(call, args, 2); err != nil {
+			return err
+		}
+		array, err := arrayArgument(call, args, "push")
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, len(array.Elements), len(array.Elements)+1)
+		copy(elements, array.Elements)
+		return &object.Array{Elements: append(elements, args[1])}
+	}},
+	"first": {Name: "first", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if err := checkArgumentCount(call, args, 1); err != nil {
+			return err
+		}
+		array, err := arrayArgument(call, args, "first")
+		if err != nil {
+			return err
+		}
+		if len(array.Elements) == 0 {
+			return NULL
+		}
+		return array.Elements[0]
+	}},
+	"last": {Name: "last", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if err := checkArgumentCount(call, args, 1); err != nil {
+			return err
+		}
+		array, err := arrayArgument(call, args, "last")
+		if err != nil {
+			return err
+		}
+		if len(array.Elements) == 0 {
+			return NULL
+		}
+		return array.Elements[len(array.Elements)-1]
+	}},
+	// Returns a new array with every element but the first, or null if there are none
+	"rest": {Name: "rest", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if err := checkArgumentCount(call, args, 1); err != nil {
+			return err
+		}
+		array, err := arrayArgument(call, args, "rest")
+		if err != nil {
+			return err
+		}
+		if len(array.Elements) == 0 {
+			return NULL
+		}
+		elements := make([]object.Object, len(array.Elements)-1)
+		copy(elements, array.Elements[1:])
+		return &object.Array{Elements: elements}
+	}},
+	// Returns the keys of a hash as an array, in the order they were added
+	"keys": {Name: "keys", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if err := checkArgumentCount(call, args, 1); err != nil {
+			return err
+		}
+		hash, err := hashArgument(call, args, "keys")
+		if err != nil {
+			return err
+		}
+		keys := []object.Object{}
+		for _, pair := range hash.Pairs() {
+			keys = append(keys, pair.Key)
+		}
+		return &object.Array{Elements: keys}
+	}},
+	// Returns the values of a hash as an array, in the order their keys were added
+	"values": {Name: "values", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if err := checkArgumentCount(call, args, 1); err != nil {
+			return err
+		}
+		hash, err := hashArgument(call, args, "values")
+		if err != nil {
+			return err
+		}
+		values := []object.Object{}
+		for _, pair := range hash.Pairs() {
+			values = append(values, pair.Value)
+		}
+		return &object.Array{Elements: values}
+	}},
+	// Returns whether a hash has a value stored under the key, which tells a missing key apart from one holding null
+	"has": {Name: "has", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if err := checkArgumentCount(call, args, 2); err != nil {
+			return err
+		}
+		hash, err := hashArgument(call, args, "has")
+		if err != nil {
+			return err
+		}
+		key, err := hashKey(call.Arguments[1], args[1])
+		if err != nil {
+			return err
+		}
+		_, ok := hash.Get(key)
+		return nativeBoolToBooleanObject(ok)
+	}},
+	// Removes a key from a hash in place, returning the value that was stored under it or null if there was none
+	"delete": {Name: "delete", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if err := checkArgumentCount(call, args, 2); err != nil {
+			return err
+		}
+		hash, err := hashArgument(call, args, "delete")
+		if err != nil {
+			return err
+		}
+		key, err := hashKey(call.Arguments[1], args[1])
+		if err != nil {
+			return err
+		}
+		if value, ok := hash.Delete(key); ok {
+			return value
+		}
+		return NULL
+	}},
+	// range(end), range(start, end) or range(start, end, step), where start defaults to 0 and step to 1, ex. range(5, 0, -2) is 5, 3, 1
+	"range": {Name: "range", Fn: func(call *ast.CallExpression, args ...object.Object) object.Object {
+		if len(args) < 1 || len(args) > 3 {
+			return newError(call, "wrong number of arguments: expected 1 to 3, got %d", len(args))
+		}
+		bounds := make([]int64, len(args))
+		for i, arg := range args {
+			integer, ok := arg.(*object.Integer)
+			if !ok {
+				return newError(call.Arguments[i], "arguments to `range` must be INTEGER, got %s", arg.Type())
+			}
+			bounds[i] = integer.Value
+		}
+		r := &object.Range{Step: 1}
+		switch len(bounds) {
+		case 1:
+			r.End = bounds[0]
+		case 2:
+			r.Start, r.End = bounds[0], bounds[1]
+		case 3:
+			r.Start, r.End, r.Step = bounds[0], bounds[1], bounds[2]
+		}
+		if r.Step == 0 {
+			return newError(call.Arguments[2], "range step cannot be 0")
+		}
+		return r
+	}},
+}
+
+func checkArgumentCount(call *ast.CallExpression, args []object.Object, expected int) *object.Error {
+	if len(args) != expected {
+		return newError(call, "wrong number of arguments: expected %d, got %d", expected, len(args))
+	}
+	return nil
+}
+
+// Returns the builtin's first argument, or an error if it isn't an array
+func arrayArgument(call *ast.CallExpression, args []object.Object, name string) (*object.Array, *object.Error) {
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, newError(call.Arguments[0], "argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	return array, nil
+}
+
+// Returns the builtin's first argument, or an error if it isn't a hash
+func hashArgument(call *ast.CallExpression, args []object.Object, name string) (*object.Hash, *object.Error) {
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, newError(call.Arguments[0], "argument to `%s` must be HASH, got %s", name, args[0].Type())
+	}
+	return hash, nil
+}
@@ -0,0 +1,151 @@
+package evaluator
+
+import (
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/ast"
+)
+
+/*
+Reads one element out of an array, one character out of a string or the value stored under a key in a hash
+Negative indices count back from the end, so -1 is the last element
+Looking up a key that isn't in a hash gives null
+*/
+func evalIndexExpression(node *ast.IndexExpression, left, index object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Hash:
+		key, err := hashKey(node.Index, index)
+		if err != nil {
+			return err
+		}
+		if value, ok := left.Get(key); ok {
+			return value
+		}
+		return NULL
+	case *object.Array:
+		i, err := resolveIndex(node.Index, index, len(left.Elements))
+		if err != nil {
+			return err
+		}
+		return left.Elements[i]
+	case *object.String:
+		chars := []rune(left.Value)
+		i, err := resolveIndex(node.Index, index, len(chars))
+		if err != nil {
+			return err
+		}
+		return &object.String{Value: string(chars[i])}
+	default:
+		return newError(node, "index operator not supported: %s", left.Type())
+	}
+}
+
+/*
+Copies part of an array or string, from low up to (but not including) high
+A missing bound means the start or end, and negative bounds count back from the end like indices do
+*/
+func evalSliceExpression(node *ast.SliceExpression, left, low, high object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		from, to, err := resolveSlice(node, low, high, len(left.Elements))
+		if err != nil {
+			return err
+		}
+		elements := make([]object.Object, to-from)
+		copy(elements, left.Elements[from:to])
+		return &object.Array{Elements: elements}
+	case *object.String:
+		chars := []rune(left.Value)
+		from, to, err := resolveSlice(node, low, high, len(chars))
+		if err != nil {
+			return err
+		}
+		return &object.String{Value: string(chars[from:to])}
+	default:
+		return newError(node, "slice operator not supported: %s", left.Type())
+	}
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+		hashable, err := hashKey(pair.Key, key)
+		if err != nil {
+			return err
+		}
+		value := Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+		hash.Set(hashable, value)
+	}
+	return hash
+}
+
+// Returns the value as a hash key, or an error pointing at node if it can't be one
+func hashKey(node ast.Node, key object.Object) (object.Hashable, *object.Error) {
+	hashable, ok := key.(object.Hashable)
+	if !ok {
+		return nil, newError(node, "unusable as hash key: %s", key.Type())
+	}
+	return hashable, nil
+}
+
+// Turns an index into a position within a collection of the given length, or an error pointing at the index if it's out of range
+func resolveIndex(node ast.Node, index object.Object, length int) (int, *object.Error) {
+	i, err := integerIndex(node, index)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		i += int64(length)
+	}
+	if i < 0 || i >= int64(length) {
+		return 0, newError(node, "index out of range: %s (length %d)", index.Inspect(), length)
+	}
+	return int(i), nil
+}
+
+// Turns the bounds of a slice into positions within a collection of the given length, or an error if they are out of range
+func resolveSlice(node *ast.SliceExpression, low, high object.Object, length int) (int, int, *object.Error) {
+	bound := func(exp ast.Expression, value object.Object, missing int) (int64, *object.Error) {
+		if value == nil {
+			return int64(missing), nil
+		}
+		i, err := integerIndex(exp, value)
+		if err != nil {
+			return 0, err
+		}
+		if i < 0 {
+			i += int64(length)
+		}
+		return i, nil
+	}
+	from, err := bound(node.Low, low, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := bound(node.High, high, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	if from < 0 || to > int64(length) || from > to {
+		return 0, 0, newError(node, "slice bounds out of range: [%d:%d] (length %d)", from, to, length)
+	}
+	return int(from), int(to), nil
+}
+
+// Indices have to be integers, and any integer too big for an int64 is definitely out of range
+func integerIndex(node ast.Node, index object.Object) (int64, *object.Error) {
+	switch index := index.(type) {
+	case *object.Integer:
+		return index.Value, nil
+	case *object.BigInteger:
+		return 0, newError(node, "index out of range: %s", index.Inspect())
+	default:
+		return 0, newError(node, "index must be an INTEGER, got %s", index.Type())
+	}
+}
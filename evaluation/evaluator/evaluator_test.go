@@ -0,0 +1,1101 @@
+package evaluator
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/lexer"
+	"github.com/ajtroup1/interpreters/parsing/parser"
+	"github.com/ajtroup1/interpreters/util"
+)
+
+func TestEvalIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"5", 5},
+		{"10", 10},
+		{"-5", -5},
+		{"--10", 10},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"-50 + 100 + -50", 0},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"50 / 2 * 2 + 10", 60},
+		{"2 * (5 + 10)", 30},
+		{"3 * 3 * 3 + 10", 37},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2},
+		{"7 / 2.0", 3.5},
+		{"1e3 - 1", 999},
+		{"9223372036854775807 * 2.0", 18446744073709551614},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		result, ok := evaluated.(*object.Float)
+		if !ok {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - object is not Float. got=%T (%+v)", tt.input, evaluated, evaluated)))
+			continue
+		}
+		if result.Value != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong value. got=%g, want=%g", tt.input, result.Value, tt.expected)))
+		}
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2.0", "2.0"},
+		{"0.1 + 0.2", "0.30000000000000004"},
+		{"1e21", "1e+21"},
+		{"-0.5", "-0.5"},
+	}
+
+	for _, tt := range tests {
+		if actual := testEval(t, tt.input).Inspect(); actual != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong Inspect(). got=%q, want=%q", tt.input, actual, tt.expected)))
+		}
+	}
+}
+
+func TestIntegerOverflowPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"9223372036854775807 * 9223372036854775807", "85070591730234615847396907784232501249"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+		{"100000000000000000000 * 100000000000000000000", "10000000000000000000000000000000000000000"},
+		{"0x7FFF_FFFF_FFFF_FFFF_FFFF", "604462909807314587353087"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		result, ok := evaluated.(*object.BigInteger)
+		if !ok {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - object is not BigInteger. got=%T (%+v)", tt.input, evaluated, evaluated)))
+			continue
+		}
+		if result.Type() != object.INTEGER_OBJ || result.Inspect() != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong value. got=%s, want=%s", tt.input, result.Inspect(), tt.expected)))
+		}
+	}
+
+	// Results that fit in 64 bits again go back to being regular integers
+	testIntegerObject(t, testEval(t, "let big = 9223372036854775807 + 10; big - 20"), 9223372036854775797)
+	testIntegerObject(t, testEval(t, "100000000000000000000 / 100000000000000000000"), 1)
+	testIntegerObject(t, testEval(t, "-9223372036854775807 - 1"), -9223372036854775807-1)
+}
+
+func TestMixedNumberComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1 == 1.0", true},
+		{"1 < 1.5", true},
+		{"2.5 > 3", false},
+		{"100000000000000000000 > 9223372036854775807", true},
+		{"100000000000000000000 == 100000000000000000000", true},
+		{"100000000000000000000 != 1", true},
+		{"100000000000000000000 < 1e30", true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"true == true", true},
+		{"false == false", true},
+		{"true == false", false},
+		{"true != false", true},
+		{"(1 < 2) == true", true},
+		{"(1 > 2) == true", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestBangOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"!true", false},
+		{"!false", true},
+		{"!5", false},
+		{"!0", false},
+		{"!!true", true},
+		{"!!5", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestIfElseExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1) { 10 }", 10},
+		{"if (0) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (if (false) { 1 }) { 10 } else { 20 }", 20},
+		{"let x = 5; if (x < 0) { -1 } else if (x == 0) { 0 } else { 1 }", 1},
+		{"let x = 0; if (x < 0) { -1 } else if (x == 0) { 0 } else { 1 }", 0},
+		{"let x = -3; if (x < 0) { -1 } else if (x == 0) { 0 } else { 1 }", -1},
+		{"let x = 3; if (x < 0) { -1 } else if (x == 0) { 0 }", nil},
+		{"if (true) { }", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestStringLiteral(t *testing.T) {
+	evaluated := testEval(t, `"Hello World!"`)
+	testStringObject(t, evaluated, "Hello World!")
+}
+
+func TestStringConcatenation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"Hello" + " " + "World!"`, "Hello World!"},
+		{`let greet = fn(name) { "Hi, " + name }; greet("Clear")`, "Hi, Clear"},
+		{`"tab\tand\nnewline"`, "tab\tand\nnewline"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"a" + "b" == "ab"`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "Clear"; "hello ${name}"`, "hello Clear"},
+		{`let x = 4; "${x} squared is ${x * x}"`, "4 squared is 16"},
+		{`"${true} and ${if (false) { 1 }}"`, "true and null"},
+		{`let f = fn(s) { "<" + s + ">" }; "${f("${1 + 1}")}"`, "<2>"},
+		{`"literal \${x}"`, "literal ${x}"},
+	}
+
+	for _, tt := range tests {
+		testStringObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"return 10;", 10},
+		{"return 10; 9;", 10},
+		{"return 2 * 5; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{"let a = 1; return a; let a = 2; a;", 1},
+		{`
+		if (10 > 1) {
+			if (10 > 1) {
+				return 10;
+			}
+			return 1;
+		}`, 10},
+		{`
+		let f = fn(x) {
+			if (x > 1) { return 1; }
+			return 2;
+		};
+		f(5) + f(0) * 10;`, 21},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"foobar", "identifier not found: foobar"},
+		{"let x = 10 / 0; x;", "division by zero"},
+		{"1.5 / 0", "division by zero"},
+		{"100000000000000000000 / 0", "division by zero"},
+		{"-\"a\"", "unknown operator: -STRING"},
+		{"1.5 + true", "type mismatch: FLOAT + BOOLEAN"},
+		{"let y = -true; return 5;", "unknown operator: -BOOLEAN"},
+		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+		{`"a" + 1`, "type mismatch: STRING + INTEGER"},
+		{`"value: ${missing}"`, "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf(util.RedText(fmt.Sprintf("no error object returned. got=%T(%+v)", evaluated, evaluated)))
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf(util.RedText(fmt.Sprintf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)))
+		}
+	}
+}
+
+func TestErrorSpan(t *testing.T) {
+	input := "let a = 5;\nlet b = a * missing;"
+	evaluated := testEval(t, input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf(util.RedText(fmt.Sprintf("no error object returned. got=%T(%+v)", evaluated, evaluated)))
+	}
+	if actual := input[errObj.Span.Start.Offset:errObj.Span.End.Offset]; actual != "missing" {
+		t.Errorf(util.RedText(fmt.Sprintf("error points at the wrong source. got=%q", actual)))
+	}
+	if errObj.Span.Start.Line != 2 {
+		t.Errorf(util.RedText(fmt.Sprintf("error on wrong line. got=%d", errObj.Span.Start.Line)))
+	}
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+
+	if evaluated := testEval(t, "let a = 5;"); evaluated != NULL {
+		t.Errorf(util.RedText(fmt.Sprintf("let statement should evaluate to null. got=%T(%+v)", evaluated, evaluated)))
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	evaluated := testEval(t, "fn(x) { x + 2; };")
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf(util.RedText(fmt.Sprintf("object is not Function. got=%T (%+v)", evaluated, evaluated)))
+	}
+	if len(fn.Parameters) != 1 || fn.Parameters[0].String() != "x" {
+		t.Fatalf(util.RedText(fmt.Sprintf("function has wrong parameters. Parameters=%+v", fn.Parameters)))
+	}
+	if fn.Body.String() != "(x + 2)" {
+		t.Fatalf(util.RedText(fmt.Sprintf("body is not %q. got=%q", "(x + 2)", fn.Body.String())))
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+		{"let early = fn() { return 1; 2; }; early() + 10;", 11},
+		{"let noop = fn() { }; let x = 3; noop(); x", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+		let newAdder = fn(x) {
+			fn(y) { x + y };
+		};
+		let addTwo = newAdder(2);
+		addTwo(2);`, 4},
+		// The body sees the environment it was defined in, not the one it was called from
+		{`
+		let x = 1;
+		let getX = fn() { x };
+		let shadow = fn(x) { getX() };
+		shadow(100);`, 1},
+		// Higher-order functions
+		{`
+		let add = fn(a, b) { a + b };
+		let applyFunc = fn(a, b, func) { func(a, b) };
+		applyFunc(2, 2, add);`, 4},
+		{`
+		let compose = fn(f, g) { fn(x) { g(f(x)) } };
+		let inc = fn(x) { x + 1 };
+		let double = fn(x) { x * 2 };
+		compose(inc, double)(5);`, 12},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`
+		let factorial = fn(n) { if (n < 2) { 1 } else { n * factorial(n - 1) } };
+		factorial(10);`, 3628800},
+		{`
+		let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+		fib(15);`, 610},
+		{`
+		let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+		let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+		if (isEven(10)) { 1 } else { 0 };`, 1},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestSelfReference(t *testing.T) {
+	// A function's name is bound by the time it's called, so the body can refer back to it
+	testBooleanObject(t, testEval(t, "let f = fn() { f }; f()() == f"), true)
+}
+
+func TestFunctionErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let f = fn(x) { x }; f(1, 2)", "wrong number of arguments: expected 1, got 2"},
+		{"let f = fn(x, y) { x }; f()", "wrong number of arguments: expected 2, got 0"},
+		{"5(1)", "not a function: INTEGER"},
+		{"let f = fn(x) { x }; f(missing)", "identifier not found: missing"},
+		{"let f = fn() { 1 + true }; f(); 5", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf(util.RedText(fmt.Sprintf("no error object returned. got=%T(%+v)", evaluated, evaluated)))
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf(util.RedText(fmt.Sprintf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)))
+		}
+	}
+}
+
+func TestEnclosedEnvironment(t *testing.T) {
+	outer := object.NewEnvironment()
+	outer.Set("a", &object.Integer{Value: 1})
+	outer.Set("b", &object.Integer{Value: 2})
+
+	inner := object.NewEnclosedEnvironment(outer)
+	inner.Set("b", &object.Integer{Value: 3})
+
+	testIntegerObject(t, Eval(parser.New(lexer.New("a + b")).ParseProgram(), inner), 4)
+	testIntegerObject(t, Eval(parser.New(lexer.New("a + b")).ParseProgram(), outer), 3)
+}
+
+func TestArrayLiterals(t *testing.T) {
+	evaluated := testEval(t, "[1, 2 * 2, 3 + 3]")
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf(util.RedText(fmt.Sprintf("object is not Array. got=%T (%+v)", evaluated, evaluated)))
+	}
+	if len(result.Elements) != 3 {
+		t.Fatalf(util.RedText(fmt.Sprintf("array has wrong number of elements. got=%d", len(result.Elements))))
+	}
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i]", 1},
+		{"[1, 2, 3][1 + 1]", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2]", 6},
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-3]", 1},
+		{"[[1, 2], [3]][0][1]", 2},
+		{`"héllo"[1]`, "é"},
+		{`"abc"[-1]`, "c"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4][2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3, 4][-2:]", "[3, 4]"},
+		{"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+		{"[1, 2][1:1]", "[]"},
+		{"[1, 2][2:]", "[]"},
+		{`"hello"[1:4]`, "ell"},
+		{`"héllo"[:2]`, "hé"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if isError(evaluated) || evaluated.Inspect() != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: wrong slice. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())))
+		}
+	}
+}
+
+func TestCollectionErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+		expectedSource  string // the part of the input the error points at
+	}{
+		{"[1, 2, 3][3]", "index out of range: 3 (length 3)", "3"},
+		{"let a = [1];\na[-2]", "index out of range: -2 (length 1)", "-2"},
+		{"[][0]", "index out of range: 0 (length 0)", "0"},
+		{"[1][100000000000000000000]", "index out of range: 100000000000000000000", "100000000000000000000"},
+		{`"abc"[5]`, "index out of range: 5 (length 3)", "5"},
+		{`[1]["a"]`, "index must be an INTEGER, got STRING", `"a"`},
+		{"5[0]", "index operator not supported: INTEGER", "5[0]"},
+		{"[1, 2][1:0]", "slice bounds out of range: [1:0] (length 2)", "[1, 2][1:0]"},
+		{"[1, 2][:3]", "slice bounds out of range: [0:3] (length 2)", "[1, 2][:3]"},
+		{"[1, 2][true:]", "index must be an INTEGER, got BOOLEAN", "true"},
+		{"5[1:]", "slice operator not supported: INTEGER", "5[1:]"},
+		{"[1, missing]", "identifier not found: missing", "missing"},
+		{`{1.5: 1}`, "unusable as hash key: FLOAT", "1.5"},
+		{`{"a": 1}[[1]]`, "unusable as hash key: ARRAY", "[1]"},
+		{`let h = {}; h[fn() {}] = 1`, "unusable as hash key: FUNCTION", "fn() {}"},
+		{`let a = [1]; "ab"[0] = "c"`, "index assignment not supported: STRING", `"ab"[0]`},
+		{`{missing: 1}`, "identifier not found: missing", "missing"},
+		{`let h = {}; h["a"] = missing`, "identifier not found: missing", "missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)))
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf(util.RedText(fmt.Sprintf("wrong error message. expected=%q, got=%q", tt.expectedMessage, errObj.Message)))
+		}
+		if actual := tt.input[errObj.Span.Start.Offset:errObj.Span.End.Offset]; actual != tt.expectedSource {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: error points at the wrong source. expected=%q, got=%q", tt.input, tt.expectedSource, actual)))
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{} // int, string for an Inspect, nil for null, or an error message
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("héllo")`, 5},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`len(1)`, errorMessage("argument to `len` not supported, got INTEGER")},
+		{`len("one", "two")`, errorMessage("wrong number of arguments: expected 1, got 2")},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, nil},
+		{`first(1)`, errorMessage("argument to `first` must be ARRAY, got INTEGER")},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, nil},
+		{`rest([1, 2, 3])`, "[2, 3]"},
+		{`rest([1])`, "[]"},
+		{`rest([])`, nil},
+		{`push([], 1)`, "[1]"},
+		{`let a = [1]; let b = push(a, 2); a`, "[1]"},
+		{`let a = [1, 2]; rest(a); a`, "[1, 2]"},
+		{`push(1, 1)`, errorMessage("argument to `push` must be ARRAY, got INTEGER")},
+		{`let len = fn(x) { 42 }; len([])`, 42},
+		{`len`, "builtin function len"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf(util.RedText(fmt.Sprintf("%s: wrong value. expected=%s, got=%s", tt.input, expected, evaluated.Inspect())))
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf(util.RedText(fmt.Sprintf("%s: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)))
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf(util.RedText(fmt.Sprintf("wrong error message. expected=%q, got=%q", expected, errObj.Message)))
+			}
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6,
+		100000000000000000000: 7
+	}`
+	evaluated := testEval(t, input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf(util.RedText(fmt.Sprintf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)))
+	}
+
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
+		{&object.BigInteger{Value: new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)}, 7},
+	}
+	if result.Len() != len(expected) {
+		t.Fatalf(util.RedText(fmt.Sprintf("Hash has wrong number of pairs. got=%d", result.Len())))
+	}
+	for i, pair := range result.Pairs() {
+		if pair.Key.HashKey() != expected[i].key.HashKey() {
+			t.Errorf(util.RedText(fmt.Sprintf("pair %d has the wrong key. expected=%s, got=%s", i, expected[i].key.Inspect(), pair.Key.Inspect())))
+		}
+		testIntegerObject(t, pair.Value, expected[i].value)
+	}
+}
+
+func TestHashKeys(t *testing.T) {
+	hello1 := &object.String{Value: "Hello World"}
+	hello2 := &object.String{Value: "Hello World"}
+	diff := &object.String{Value: "My name is johnny"}
+	if hello1.HashKey() != hello2.HashKey() {
+		t.Errorf(util.RedText("strings with same content have different hash keys"))
+	}
+	if hello1.HashKey() == diff.HashKey() {
+		t.Errorf(util.RedText("strings with different content have same hash keys"))
+	}
+	if (&object.Integer{Value: 1}).HashKey() == TRUE.HashKey() {
+		t.Errorf(util.RedText("1 and true have the same hash key"))
+	}
+	if (&object.String{Value: "1"}).HashKey().Type == (&object.Integer{Value: 1}).HashKey().Type {
+		t.Errorf(util.RedText("strings and integers have the same hash key type"))
+	}
+
+	// Keys are stored by their whole value, so every distinct string and big integer keeps its own entry
+	hash := object.NewHash()
+	for i := range 10_000 {
+		bigKey := new(big.Int).Lsh(big.NewInt(int64(i)), 64)
+		hash.Set(&object.String{Value: fmt.Sprint("key", i)}, &object.Integer{Value: int64(i)})
+		hash.Set(&object.BigInteger{Value: bigKey}, &object.Integer{Value: int64(-i)})
+	}
+	if hash.Len() != 20_000 {
+		t.Fatalf(util.RedText(fmt.Sprintf("distinct keys overwrote each other. expected 20000 entries, got %d", hash.Len())))
+	}
+	for i := range 10_000 {
+		value, ok := hash.Get(&object.String{Value: fmt.Sprint("key", i)})
+		if !ok || value.(*object.Integer).Value != int64(i) {
+			t.Fatalf(util.RedText(fmt.Sprintf("key%d found the wrong value. got=%v", i, value)))
+		}
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{"a": {"b": 2}}["a"]["b"]`, 2},
+		{`let h = {}; h["a"] = 1; h["a"]`, 1},
+		{`let h = {"a": 1}; h["a"] = h["a"] + 1; h["a"]`, 2},
+		{`let h = {}; h[1] = 5`, 5},
+		{`let h = {}; let g = h; g["x"] = 3; h["x"]`, 3},
+		{`let h = {}; h["x"] = h["y"] = 4; h["x"] + h["y"]`, 8},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if integer, ok := tt.expected.(int); ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let h = {"b": 1, "a": [true]}; h["c"] = {}["missing"]; h["b"] = 2; h`, `{"b": 2, "a": [true], "c": null}`},
+		// Strings inside a collection are quoted, so they can't be mistaken for other values
+		{`{"1": 1, 1: 2}`, `{"1": 1, 1: 2}`},
+		{`["a", "a\"b", ["c"], {"d": "e"}, 1]`, `["a", "a\"b", ["c"], {"d": "e"}, 1]`},
+		{`"${["x"]}"`, `["x"]`},
+	}
+
+	for _, tt := range tests {
+		if actual := testEval(t, tt.input).Inspect(); actual != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: wrong Inspect. expected=%s, got=%s", tt.input, tt.expected, actual)))
+		}
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{} // int, string for an Inspect, or an error message
+	}{
+		{`len({"a": 1, "b": 2})`, 2},
+		{`keys({"b": 1, 2: 2, true: 3})`, `["b", 2, true]`},
+		{`keys({})`, "[]"},
+		{`values({"b": 1, "a": [2]})`, "[1, [2]]"},
+		{`has({"a": {}["missing"]}, "a")`, "true"},
+		{`has({"a": 1}, "b")`, "false"},
+		{`let h = {"a": 1, "b": 2}; delete(h, "a")`, 1},
+		{`let h = {"a": 1, "b": 2}; delete(h, "a"); h`, `{"b": 2}`},
+		{`let h = {"a": 1}; delete(h, "zz")`, "null"},
+		{`let h = {"a": 1, "b": 2}; delete(h, "a"); h["a"] = 3; keys(h)`, `["b", "a"]`},
+		{`keys([1])`, errorMessage("argument to `keys` must be HASH, got ARRAY")},
+		{`has({}, [1])`, errorMessage("unusable as hash key: ARRAY")},
+		{`delete({}, fn() {})`, errorMessage("unusable as hash key: FUNCTION")},
+		{`values({}, 1)`, errorMessage("wrong number of arguments: expected 1, got 2")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf(util.RedText(fmt.Sprintf("%s: wrong value. expected=%s, got=%s", tt.input, expected, evaluated.Inspect())))
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf(util.RedText(fmt.Sprintf("%s: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)))
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf(util.RedText(fmt.Sprintf("wrong error message. expected=%q, got=%q", expected, errObj.Message)))
+			}
+		}
+	}
+}
+
+// Marks an expected value in a test table as the message of an expected error
+type errorMessage string
+
+func testEval(t *testing.T, input string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf(util.RedText(fmt.Sprintf("parser errors for %q: %v", input, p.Errors())))
+	}
+	env := object.NewEnvironment()
+	return Eval(program, env)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	t.Helper()
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf(util.RedText(fmt.Sprintf("object is not Integer. got=%T (%+v)", obj, obj)))
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf(util.RedText(fmt.Sprintf("object has wrong value. got=%d, want=%d", result.Value, expected)))
+		return false
+	}
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	t.Helper()
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf(util.RedText(fmt.Sprintf("object is not String. got=%T (%+v)", obj, obj)))
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf(util.RedText(fmt.Sprintf("object has wrong value. got=%q, want=%q", result.Value, expected)))
+		return false
+	}
+	return true
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+	t.Helper()
+	if obj != NULL {
+		t.Errorf(util.RedText(fmt.Sprintf("object is not NULL. got=%T (%+v)", obj, obj)))
+		return false
+	}
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+	t.Helper()
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf(util.RedText(fmt.Sprintf("object is not Boolean. got=%T (%+v)", obj, obj)))
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf(util.RedText(fmt.Sprintf("object has wrong value. got=%t, want=%t", result.Value, expected)))
+		return false
+	}
+	return true
+}
+
+func TestAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{} // int, string for an Inspect, or an error message
+	}{
+		{"let x = 1; x = x + 1; x", 2},
+		{"let x = 1; x = 5", 5},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", 6},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let x = 1; let y = 2; x = y = 3; x + y", 6},
+		{"let x = 1; let f = fn() { x = 2 }; f(); x", 2},
+		{"let x = 1; let f = fn() { let x = 5; x = 6 }; f(); x", 1},
+		{"let a = [1, 2, 3]; a[0] = 10; a[-1] += 1; a", "[10, 2, 4]"},
+		{"let a = [1]; let b = a; b[0] = 2; a", "[2]"},
+		{`let h = {"n": 1}; h["n"] += 1; h["n"]`, 2},
+		{"let a = [[1]]; a[0][0] = 2; a", "[[2]]"},
+		{"missing = 1", errorMessage("identifier not found: missing")},
+		{"missing += 1", errorMessage("identifier not found: missing")},
+		{"let x = true; x += 1", errorMessage("type mismatch: BOOLEAN + INTEGER")},
+		{"let a = [1]; a[1] = 2", errorMessage("index out of range: 1 (length 1)")},
+		{`let h = {}; h["a"] += 1`, errorMessage("type mismatch: NULL + INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestConstants(t *testing.T) {
+	testIntegerObject(t, testEval(t, "const x = 5; x * 2"), 10)
+	testIntegerObject(t, testEval(t, "const x = 5; let x = 6; x = 7; x"), 7)
+
+	// A constant made by an earlier program, like an earlier line in the REPL, is only caught when the assignment runs
+	env := object.NewEnvironment()
+	for _, input := range []string{"const x = 5;", "x = 6", "x += 1"} {
+		p := parser.New(lexer.New(input))
+		evaluated := Eval(p.ParseProgram(), env)
+		if input == "const x = 5;" {
+			continue
+		}
+		testValue(t, input, evaluated, errorMessage("cannot assign to x, it is a constant"))
+	}
+	value, _ := env.Get("x")
+	testIntegerObject(t, value, 5)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{} // int, string for an Inspect, nil for null, or an error message
+	}{
+		{"let i = 0; while (i < 5) { i += 1 }; i", 5},
+		{"let i = 0; while (false) { i += 1 }; i", 0},
+		{"while (false) {}", nil},
+		{"let s = 0; for (let i = 0; i < 5; i += 1) { s += i }; s", 10},
+		{"let i = 0; for (; i < 3;) { i += 1 }; i", 3},
+		{"let i = 0; for (;;) { i += 1; if (i == 4) { break } }; i", 4},
+		{"let s = 0; for (let i = 0; i < 6; i += 1) { if (i == 2) { continue } s += i }; s", 13},
+		{"let s = 0; for (x in [1, 2, 3]) { s += x }; s", 6},
+		{`let s = ""; for (k in {"b": 1, "a": 2}) { s += k }; s`, "ba"},
+		{`let s = []; for (c in "héllo") { s = push(s, c) }; s`, `["h", "é", "l", "l", "o"]`},
+		{"let s = []; for (i in range(3)) { s = push(s, i) }; s", "[0, 1, 2]"},
+		{"let s = []; for (i in range(10, 0, -4)) { s = push(s, i) }; s", "[10, 6, 2]"},
+		{"let s = 0; for (i in range(5, 0)) { s += 1 }; s", 0},
+		{"let a = [1, 2]; for (x in a) { a = push(a, x) }; a", "[1, 2, 1, 2]"},
+		{"let x = 1; for (x in [5, 6]) {}; x", 1},
+		{"let fs = []; for (i in [1, 2]) { fs = push(fs, fn() { i }) }; fs[0]()", 1},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10 } } }; f()", 20},
+		{"let s = []; outer: for (i in range(3)) { for (j in range(3)) { if (j == 1) { continue outer } s = push(s, [i, j]) } }; s", "[[0, 0], [1, 0], [2, 0]]"},
+		{"let n = 0; outer: while (true) { while (true) { n += 1; break outer } }; n", 1},
+		{"let n = 0; for (i in range(3)) { for (j in range(3)) { if (j == 1) { break } n += 1 } }; n", 3},
+		{"for (x in 5) {}", errorMessage("cannot loop over INTEGER")},
+		{"while (missing) {}", errorMessage("identifier not found: missing")},
+		{"for (let i = 0; i < 3; i += true) {}", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{} // int, string for an Inspect, or an error message
+	}{
+		{"range(3)", "range(0, 3)"},
+		{"range(1, 10, 3)", "range(1, 10, 3)"},
+		{"len(range(10))", 10},
+		{"len(range(1, 10, 3))", 3},
+		{"len(range(10, 0, -3))", 4},
+		{"len(range(5, 1))", 0},
+		// Ranges spanning more than the largest integer still count (and loop) correctly
+		{"len(range(-9223372036854775807 - 1, 9223372036854775807))", 9223372036854775807},
+		{"len(range(-1, 9223372036854775807))", 9223372036854775807},
+		{"len(range(0, 9223372036854775807, 4611686018427387904))", 2},
+		{"len(range(9223372036854775807, -9223372036854775807 - 1, -9223372036854775807 - 1))", 2},
+		{"let n = 0; for (i in range(-1, 9223372036854775807)) { n += 1; if (n == 3) { break } }; n", 3},
+		{"let s = []; for (i in range(9223372036854775805, 9223372036854775807)) { s = push(s, i) }; s", "[9223372036854775805, 9223372036854775806]"},
+		{"let s = []; for (i in range(9223372036854775807, -9223372036854775807 - 1, -9223372036854775807 - 1)) { s = push(s, i) }; s", "[9223372036854775807, -1]"},
+		{"range()", errorMessage("wrong number of arguments: expected 1 to 3, got 0")},
+		{`range("a")`, errorMessage("arguments to `range` must be INTEGER, got STRING")},
+		{"range(0, 5, 0)", errorMessage("range step cannot be 0")},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+// Checks a value against an expectation from a test table: an int, a string for an Inspect, nil for null, or an error message
+func testValue(t *testing.T, input string, evaluated object.Object, expected interface{}) {
+	t.Helper()
+	switch expected := expected.(type) {
+	case int:
+		testIntegerObject(t, evaluated, int64(expected))
+	case nil:
+		testNullObject(t, evaluated)
+	case string:
+		if evaluated.Inspect() != expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: wrong value. expected=%s, got=%s", input, expected, evaluated.Inspect())))
+		}
+	case errorMessage:
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: no error object returned. got=%T(%+v)", input, evaluated, evaluated)))
+			return
+		}
+		if errObj.Message != string(expected) {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: wrong error message. expected=%q, got=%q", input, expected, errObj.Message)))
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{} // int, string for an Inspect, or an error message
+	}{
+		{"1 <= 1", "true"},
+		{"2 <= 1", "false"},
+		{"1 >= 2", "false"},
+		{"1.5 >= 1", "true"},
+		{"9223372036854775808 >= 9223372036854775807", "true"},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7.5 % 2", "1.5"},
+		{"9223372036854775808 % 10", 8},
+		{"1 % 0", errorMessage("division by zero")},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"2 ** 64", "18446744073709551616"},
+		{"2 ** -1", "0.5"},
+		{"2.0 ** 0.5 > 1.41", "true"},
+		{"1 ** 100000000000", 1},
+		{"10 ** 100000000000", errorMessage("integer too large: the result of ** would have more than 16777216 bits")},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"~-1", 0},
+		{"1 << 4", 16},
+		{"1 << 63", "9223372036854775808"},
+		{"-16 >> 2", -4},
+		{"1 >> 100", 0},
+		{"18446744073709551616 >> 60", 16},
+		{"18446744073709551616 & 18446744073709551617", "18446744073709551616"},
+		{"1 << -1", errorMessage("negative shift count: -1")},
+		{"1 >> -1", errorMessage("negative shift count: -1")},
+		{"1.5 & 1", errorMessage("unknown operator: FLOAT & INTEGER")},
+		{"~1.5", errorMessage("unknown operator: ~FLOAT")},
+		{"true | false", errorMessage("unknown operator: BOOLEAN | BOOLEAN")},
+		{`"a" <= "b"`, errorMessage("unknown operator: STRING <= STRING")},
+		{"let x = 1; x += 2 ** 3 % 5; x", 4},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{} // int, string for an Inspect, or an error message
+	}{
+		{"true && true", "true"},
+		{"true && false", "false"},
+		{"false || true", "true"},
+		{"false || false", "false"},
+		{"1 && \"a\"", "true"},
+		{"{}[0] || 0", "true"},
+		{"false || {}[0]", "false"},
+		{"1 < 2 && 2 < 3", "true"},
+		// The right side only runs when it is needed
+		{"let n = 0; let f = fn() { n += 1; true }; false && f(); true || f(); n", 0},
+		{"let n = 0; let f = fn() { n += 1; true }; true && f(); false || f(); n", 2},
+		{"false && missing", "false"},
+		{"true || missing", "true"},
+		{"true && missing", errorMessage("identifier not found: missing")},
+		{"missing || true", errorMessage("identifier not found: missing")},
+	}
+
+	for _, tt := range tests {
+		testValue(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
@@ -0,0 +1,159 @@
+package evaluator
+
+import (
+	"iter"
+
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/ast"
+)
+
+// Loops are statements, so once they finish they evaluate to null
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+		if done, result := evalLoopBody(node.Label, node.Body, env); done {
+			return result
+		}
+	}
+}
+
+/*
+The init, condition, step and body all share one environment made for the loop
+That keeps a counter declared by the init visible to the rest of the loop, but not after it
+*/
+func evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	loopEnv := object.NewEnclosedEnvironment(env)
+	if node.Init != nil {
+		if init := Eval(node.Init, loopEnv); isError(init) {
+			return init
+		}
+	}
+	for {
+		if node.Condition != nil {
+			condition := Eval(node.Condition, loopEnv)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return NULL
+			}
+		}
+		if done, result := evalLoopBody(node.Label, node.Body, loopEnv); done {
+			return result
+		}
+		if node.Step != nil {
+			if step := Eval(node.Step, loopEnv); isError(step) {
+				return step
+			}
+		}
+	}
+}
+
+/*
+Every pass gets a fresh environment with the variable bound to the next item
+A closure made in the body therefore keeps the item from its own pass
+*/
+func evalForInStatement(node *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+	items, err := iterate(node.Iterable, iterable)
+	if err != nil {
+		return err
+	}
+	for item := range items {
+		passEnv := object.NewEnclosedEnvironment(env)
+		passEnv.Set(node.Variable.Value, item)
+		if done, result := evalLoopBody(node.Label, node.Body, passEnv); done {
+			return result
+		}
+	}
+	return NULL
+}
+
+/*
+Runs one pass of a loop's body, returning whether the loop is done and if so what the loop evaluates to
+A break or continue without a label, or with this loop's label, is handled here
+Anything else that stops the body (a return, an error, or a break or continue meant for an outer loop) is passed on
+*/
+func evalLoopBody(label *ast.Identifier, body *ast.BlockStatement, env *object.Environment) (bool, object.Object) {
+	switch result := Eval(body, env).(type) {
+	case *object.Break:
+		if targetsLoop(label, result.Label) {
+			return true, NULL
+		}
+		return true, result
+	case *object.Continue:
+		if targetsLoop(label, result.Label) {
+			return false, nil
+		}
+		return true, result
+	case *object.ReturnValue, *object.Error:
+		return true, result
+	}
+	return false, nil
+}
+
+func targetsLoop(label *ast.Identifier, target string) bool {
+	return target == "" || labelName(label) == target
+}
+
+// Returns the label's name, or "" for a missing label
+func labelName(label *ast.Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Value
+}
+
+/*
+Returns the items a for-in loop goes through, or an error pointing at node if the value can't be looped over
+Arrays and hashes are copied first, so changing them inside the loop doesn't change what it goes through
+*/
+func iterate(node ast.Node, iterable object.Object) (iter.Seq[object.Object], *object.Error) {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		elements := append([]object.Object{}, iterable.Elements...)
+		return func(yield func(object.Object) bool) {
+			for _, element := range elements {
+				if !yield(element) {
+					return
+				}
+			}
+		}, nil
+	case *object.Hash:
+		pairs := iterable.Pairs()
+		return func(yield func(object.Object) bool) {
+			for _, pair := range pairs {
+				if !yield(pair.Key) {
+					return
+				}
+			}
+		}, nil
+	case *object.String:
+		return func(yield func(object.Object) bool) {
+			for _, char := range iterable.Value {
+				if !yield(&object.String{Value: string(char)}) {
+					return
+				}
+			}
+		}, nil
+	case *object.Range:
+		return func(yield func(object.Object) bool) {
+			for i, n := iterable.Start, iterable.Len(); n > 0; i, n = i+iterable.Step, n-1 {
+				if !yield(&object.Integer{Value: i}) {
+					return
+				}
+			}
+		}, nil
+	default:
+		return nil, newError(node, "cannot loop over %s", iterable.Type())
+	}
+}
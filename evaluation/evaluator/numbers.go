@@ -0,0 +1,302 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/ast"
+)
+
+/*
+Clear has three kinds of number: 64-bit Integers, BigIntegers for anything larger, and Floats
+Mixing them follows a simple tower, where the result takes the "widest" kind involved:
+
+	Integer op Integer     -> Integer, or BigInteger if the result overflows 64 bits
+	BigInteger op Integer  -> BigInteger, or Integer if the result fits back into 64 bits
+	Float op anything      -> Float
+
+The bitwise operators (& | ^ << >> ~) only work on integers
+*/
+func isNumber(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.BigInteger, *object.Float:
+		return true
+	default:
+		return false
+	}
+}
+
+// The largest integer, in bits, that ** and << will produce, so a typo like 10 ** 10000000000 can't use up all the memory
+const MAX_INTEGER_BITS = 1 << 24
+
+func evalMinusPrefixOperatorExpression(node *ast.PrefixExpression, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return normalizeBigInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInteger:
+		return normalizeBigInteger(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
+		return newError(node, "unknown operator: -%s", right.Type())
+	}
+}
+
+// '~' flips every bit of an integer, which for Clear's two's complement integers means ~x is -x - 1
+func evalTildePrefixOperatorExpression(node *ast.PrefixExpression, right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: ^right.Value}
+	case *object.BigInteger:
+		return normalizeBigInteger(new(big.Int).Not(right.Value))
+	default:
+		return newError(node, "unknown operator: ~%s", right.Type())
+	}
+}
+
+func evalNumberInfixExpression(node *ast.InfixExpression, left, right object.Object) object.Object {
+	_, leftIsFloat := left.(*object.Float)
+	_, rightIsFloat := right.(*object.Float)
+	leftInt, leftIsInt := left.(*object.Integer)
+	rightInt, rightIsInt := right.(*object.Integer)
+
+	switch {
+	case isBitwise(node.Operator) && (leftIsFloat || rightIsFloat):
+		return newError(node, "unknown operator: %s %s %s", left.Type(), node.Operator, right.Type())
+	case leftIsFloat || rightIsFloat:
+		return evalFloatInfixExpression(node, toFloat(left), toFloat(right))
+	case leftIsInt && rightIsInt:
+		return evalIntegerInfixExpression(node, leftInt.Value, rightInt.Value)
+	default:
+		return evalBigIntegerInfixExpression(node, toBigInt(left), toBigInt(right))
+	}
+}
+
+/*
+Integer arithmetic is checked for overflow
+Instead of silently wrapping around, an overflowing operation is redone with big integers
+*/
+func evalIntegerInfixExpression(node *ast.InfixExpression, leftVal, rightVal int64) object.Object {
+	switch node.Operator {
+	case "+":
+		result := leftVal + rightVal
+		if (result > leftVal) == (rightVal > 0) {
+			return &object.Integer{Value: result}
+		}
+	case "-":
+		result := leftVal - rightVal
+		if (result < leftVal) == (rightVal > 0) {
+			return &object.Integer{Value: result}
+		}
+	case "*":
+		result := leftVal * rightVal
+		if leftVal == 0 || (result/leftVal == rightVal && !(leftVal == -1 && rightVal == math.MinInt64)) {
+			return &object.Integer{Value: result}
+		}
+	case "/":
+		if rightVal == 0 {
+			return newError(node, "division by zero")
+		}
+		if !(leftVal == math.MinInt64 && rightVal == -1) {
+			return &object.Integer{Value: leftVal / rightVal}
+		}
+	case "%":
+		if rightVal == 0 {
+			return newError(node, "division by zero")
+		}
+		// Go defines MinInt64 % -1 as 0, so unlike division this can't overflow
+		return &object.Integer{Value: leftVal % rightVal}
+	case "**", "<<":
+		// Both can grow far past 64 bits, so they are always worked out with big integers
+	case ">>":
+		if rightVal < 0 {
+			return newError(node, "negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal >> rightVal}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(node, "unknown operator: %s %s %s", object.INTEGER_OBJ, node.Operator, object.INTEGER_OBJ)
+	}
+	// Only reached when the operation overflowed, or needs big integers anyway
+	return evalBigIntegerInfixExpression(node, big.NewInt(leftVal), big.NewInt(rightVal))
+}
+
+func evalBigIntegerInfixExpression(node *ast.InfixExpression, leftVal, rightVal *big.Int) object.Object {
+	switch node.Operator {
+	case "+":
+		return normalizeBigInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return normalizeBigInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return normalizeBigInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError(node, "division by zero")
+		}
+		// Quo truncates towards zero, just like dividing two Integers does
+		return normalizeBigInteger(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError(node, "division by zero")
+		}
+		// Rem matches Quo, so the result takes the sign of the left side like it does for Integers
+		return normalizeBigInteger(new(big.Int).Rem(leftVal, rightVal))
+	case "**":
+		return evalIntegerPower(node, leftVal, rightVal)
+	case "<<", ">>":
+		if rightVal.Sign() < 0 {
+			return newError(node, "negative shift count: %s", rightVal)
+		}
+		if node.Operator == ">>" {
+			// Rsh rounds towards negative infinity, which is the same as an arithmetic shift
+			// Shifting out every bit leaves 0, or -1 for a negative number
+			if !rightVal.IsInt64() {
+				return normalizeBigInteger(big.NewInt(int64(leftVal.Sign() >> 1)))
+			}
+			return normalizeBigInteger(new(big.Int).Rsh(leftVal, uint(rightVal.Int64())))
+		}
+		if !rightVal.IsInt64() || int64(leftVal.BitLen())+rightVal.Int64() > MAX_INTEGER_BITS {
+			return newError(node, "integer too large: the result of << would have more than %d bits", MAX_INTEGER_BITS)
+		}
+		return normalizeBigInteger(new(big.Int).Lsh(leftVal, uint(rightVal.Int64())))
+	case "&":
+		return normalizeBigInteger(new(big.Int).And(leftVal, rightVal))
+	case "|":
+		return normalizeBigInteger(new(big.Int).Or(leftVal, rightVal))
+	case "^":
+		return normalizeBigInteger(new(big.Int).Xor(leftVal, rightVal))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError(node, "unknown operator: %s %s %s", object.INTEGER_OBJ, node.Operator, object.INTEGER_OBJ)
+	}
+}
+
+func evalFloatInfixExpression(node *ast.InfixExpression, leftVal, rightVal float64) object.Object {
+	switch node.Operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError(node, "division by zero")
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError(node, "division by zero")
+		}
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(node, "unknown operator: %s %s %s", object.FLOAT_OBJ, node.Operator, object.FLOAT_OBJ)
+	}
+}
+
+/*
+Raises an integer to an integer power, exactly
+A negative exponent can't give a whole number, so it gives a Float instead, ex. 2 ** -1 is 0.5
+*/
+func evalIntegerPower(node *ast.InfixExpression, base, exponent *big.Int) object.Object {
+	if exponent.Sign() < 0 {
+		return &object.Float{Value: math.Pow(toFloat(&object.BigInteger{Value: base}), toFloat(&object.BigInteger{Value: exponent}))}
+	}
+	// 0, 1 and -1 stay small whatever the exponent, anything else has at least BitLen - 1 bits per multiplication
+	if base.CmpAbs(big.NewInt(1)) > 0 {
+		if !exponent.IsInt64() || exponent.Int64() > MAX_INTEGER_BITS || int64(base.BitLen()-1)*exponent.Int64() > MAX_INTEGER_BITS {
+			return newError(node, "integer too large: the result of ** would have more than %d bits", MAX_INTEGER_BITS)
+		}
+	}
+	return normalizeBigInteger(new(big.Int).Exp(base, exponent, nil))
+}
+
+// Bitwise operators only make sense on integers
+func isBitwise(operator string) bool {
+	switch operator {
+	case "&", "|", "^", "<<", ">>":
+		return true
+	}
+	return false
+}
+
+// Turns a big integer result back into a regular Integer whenever it fits in 64 bits
+func normalizeBigInteger(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInteger{Value: value}
+}
+
+// Converts any kind of number to a float64, big integers are rounded to the nearest float
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInteger:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
+// Converts either kind of integer to a *big.Int
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInteger:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
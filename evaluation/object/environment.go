@@ -0,0 +1,139 @@
+package object
+
+import "sort"
+
+/*
+An environment keeps track of every binding made with let or const
+Each function call gets its own environment wrapping the one the function was defined in (outer)
+Looking up a name checks the innermost environment first and works outwards, which gives Clear lexical scoping
+*/
+type Environment struct {
+	store     map[string]Object
+	constants map[string]bool // names in store that were bound with const
+	outer     *Environment
+}
+
+func NewEnvironment() *Environment {
+	s := make(map[string]Object)
+	return &Environment{store: s, constants: make(map[string]bool), outer: nil}
+}
+
+// Creates a new, empty environment whose lookups fall back to outer
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Binds the name in this environment only, shadowing any binding of the same name in an outer environment
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	delete(e.constants, name)
+	return val
+}
+
+// Binds the name in this environment only like Set, but as a constant that Assign refuses to change
+func (e *Environment) SetConst(name string, val Object) Object {
+	e.store[name] = val
+	e.constants[name] = true
+	return val
+}
+
+/*
+Changes the value of an existing binding, in whichever environment the name is bound in
+Returns whether the name was bound at all, and whether it is a constant, in which case nothing is changed
+*/
+func (e *Environment) Assign(name string, val Object) (found, constant bool) {
+	if _, ok := e.store[name]; ok {
+		if e.constants[name] {
+			return true, true
+		}
+		e.store[name] = val
+		return true, false
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false, false
+}
+
+// Returns the names bound in this environment, not counting outer ones, in alphabetical order
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+Returns a deep copy of the environment, every environment around it and every value bound in them
+Functions are copied along with the environments they close over, so calling a copied function only ever changes the copy
+Values that never change in place (numbers, strings, booleans, null, ranges and builtins) are shared instead of copied
+*/
+func (e *Environment) Copy() *Environment {
+	c := &copier{envs: map[*Environment]*Environment{}, objects: map[Object]Object{}}
+	return c.env(e)
+}
+
+// Remembers what has already been copied, so values shared between bindings (or containing themselves) stay shared in the copy
+type copier struct {
+	envs    map[*Environment]*Environment
+	objects map[Object]Object
+}
+
+func (c *copier) env(e *Environment) *Environment {
+	if e == nil {
+		return nil
+	}
+	if copied, ok := c.envs[e]; ok {
+		return copied
+	}
+	copied := NewEnvironment()
+	c.envs[e] = copied
+	copied.outer = c.env(e.outer)
+	for name, val := range e.store {
+		copied.store[name] = c.object(val)
+	}
+	for name := range e.constants {
+		copied.constants[name] = true
+	}
+	return copied
+}
+
+func (c *copier) object(obj Object) Object {
+	if copied, ok := c.objects[obj]; ok {
+		return copied
+	}
+	switch obj := obj.(type) {
+	case *Array:
+		copied := &Array{Elements: make([]Object, len(obj.Elements))}
+		c.objects[obj] = copied
+		for i, element := range obj.Elements {
+			copied.Elements[i] = c.object(element)
+		}
+		return copied
+	case *Hash:
+		copied := NewHash()
+		c.objects[obj] = copied
+		for _, pair := range obj.Pairs() {
+			copied.Set(pair.Key, c.object(pair.Value))
+		}
+		return copied
+	case *Function:
+		copied := &Function{Parameters: obj.Parameters, Body: obj.Body}
+		c.objects[obj] = copied
+		copied.Env = c.env(obj.Env)
+		return copied
+	}
+	return obj
+}
@@ -0,0 +1,116 @@
+package object
+
+import (
+	"strings"
+)
+
+/*
+The key a value is stored under in a hash
+Keys are compared by type and value rather than by pointer, so two separately created "a" strings find the same entry
+Strings and big integers are keyed by their whole text rather than a digest of it, so two different keys can never collide
+*/
+type HashKey struct {
+	Type  ObjectType
+	Value uint64 // the value of an integer or boolean
+	Text  string // the value of a string or big integer
+}
+
+// Values that can be used as hash keys: integers, booleans and strings
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// Big integers never hold a value that fits in an Integer, so their keys can't clash with an equal Integer's
+func (bi *BigInteger) HashKey() HashKey {
+	return HashKey{Type: bi.Type(), Text: bi.Value.String()}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s *String) HashKey() HashKey {
+	return HashKey{Type: s.Type(), Text: s.Value}
+}
+
+// One entry of a hash, keeping the original key so it can be shown and iterated over
+type HashPair struct {
+	Key   Hashable
+	Value Object
+}
+
+/*
+A mutable map from keys to values
+Entries are kept in the order their keys were first added, so a hash is always shown and iterated over the same way
+	Ex. {"name": "Clear", 1: true}
+*/
+type Hash struct {
+	pairs map[HashKey]HashPair
+	order []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{pairs: make(map[HashKey]HashPair)}
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	pairs := []string{}
+	for _, pair := range h.Pairs() {
+		pairs = append(pairs, inspectElement(pair.Key)+": "+inspectElement(pair.Value))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Stores the value under the key, replacing any value already there without changing the key's place in the order
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+	if _, ok := h.pairs[hashKey]; !ok {
+		h.order = append(h.order, hashKey)
+	}
+	h.pairs[hashKey] = HashPair{Key: key, Value: value}
+}
+
+// Removes the key, returning the value that was stored under it
+func (h *Hash) Delete(key Hashable) (Object, bool) {
+	hashKey := key.HashKey()
+	pair, ok := h.pairs[hashKey]
+	if !ok {
+		return nil, false
+	}
+	delete(h.pairs, hashKey)
+	for i, k := range h.order {
+		if k == hashKey {
+			h.order = append(h.order[:i], h.order[i+1:]...)
+			break
+		}
+	}
+	return pair.Value, true
+}
+
+func (h *Hash) Len() int {
+	return len(h.order)
+}
+
+// Returns every entry in the order the keys were added
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.order))
+	for i, key := range h.order {
+		pairs[i] = h.pairs[key]
+	}
+	return pairs
+}
@@ -0,0 +1,252 @@
+/*
+object
+Every value produced while evaluating a Clear program is represented as an Object
+Integers, booleans, functions, etc. all implement the same small interface so the evaluator can pass them around uniformly
+*/
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ajtroup1/interpreters/parsing/ast"
+	"github.com/ajtroup1/interpreters/parsing/token"
+)
+
+type ObjectType string
+
+const (
+	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
+	BOOLEAN_OBJ      = "BOOLEAN"
+	STRING_OBJ       = "STRING"
+	NULL_OBJ         = "NULL"
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	ERROR_OBJ        = "ERROR"
+	FUNCTION_OBJ     = "FUNCTION"
+	BUILTIN_OBJ      = "BUILTIN"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	RANGE_OBJ        = "RANGE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+)
+
+/*
+Every object has to be able to tell what kind of value it is and how it should be displayed
+	Ex. Integer = { Type(): INTEGER, Inspect(): "5" }
+*/
+type Object interface {
+	Type() ObjectType
+	Inspect() string
+}
+
+type Integer struct {
+	Value int64
+}
+
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
+
+/*
+An integer too big to fit in 64 bits
+Arithmetic on Integers that would overflow produces one of these instead of wrapping around
+To Clear code it's still just an INTEGER, the evaluator turns it back into a regular Integer as soon as the value fits again
+*/
+type BigInteger struct {
+	Value *big.Int
+}
+
+func (bi *BigInteger) Type() ObjectType { return INTEGER_OBJ }
+func (bi *BigInteger) Inspect() string  { return bi.Value.String() }
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
+// Floats always display with a decimal point or exponent, so 2.0 can't be mistaken for the integer 2
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+type Boolean struct {
+	Value bool
+}
+
+func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) Inspect() string  { return s.Value }
+
+// Null represents the absence of a value, like a let statement's result or a missing else branch
+type Null struct{}
+
+func (n *Null) Type() ObjectType { return NULL_OBJ }
+func (n *Null) Inspect() string  { return "null" }
+
+/*
+Wraps the value of a return statement
+The evaluator keeps passing the wrapper up through blocks untouched until it reaches the function or program being returned from,
+	which unwraps it, so nothing after the return statement gets evaluated
+*/
+type ReturnValue struct {
+	Value Object
+}
+
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+
+/*
+Produced by break and continue statements
+Like return values, they are passed up through blocks until they reach the loop they belong to
+Label is the label of the loop they name, or "" for the innermost loop
+*/
+type Break struct {
+	Label string
+}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct {
+	Label string
+}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
+/*
+A runtime error, such as adding an integer to a boolean
+Errors travel up the tree just like return values, stopping evaluation wherever they are produced
+Span points at the part of the source code that caused the error
+*/
+type Error struct {
+	Message string
+	Span    token.Span
+}
+
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+
+/*
+A function value, holding on to the environment it was defined in
+Keeping the environment is what makes closures work: the body can still see the bindings that were around when the function was created
+*/
+type Function struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(f.Body.String())
+	out.WriteString("\n}")
+	return out.String()
+}
+
+/*
+The signature of functions built into Clear, like len
+Call is the expression calling the builtin, so errors can point back at it
+*/
+type BuiltinFunction func(call *ast.CallExpression, args ...Object) Object
+
+// A function built into Clear and implemented in Go
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin function " + b.Name }
+
+/*
+An ordered list of values, which don't have to be of the same type
+	Ex. [1, "two", fn(x) { x }]
+*/
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, inspectElement(e))
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+/*
+Shows a value held inside an array or hash
+Strings are quoted there, otherwise ["1", 1] and [1, 1] would look the same
+	Ex. ["a", 1] is shown as ["a", 1], while the string "a" on its own is shown as a
+*/
+func inspectElement(obj Object) string {
+	if s, ok := obj.(*String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return obj.Inspect()
+}
+
+/*
+The integers from Start up to (but not including) End, counting by Step
+The integers are never stored, so a range can be huge without using any memory
+	Ex. range(0, 10, 2) is 0, 2, 4, 6, 8
+*/
+type Range struct {
+	Start int64
+	End   int64
+	Step  int64
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	if r.Step == 1 {
+		return fmt.Sprintf("range(%d, %d)", r.Start, r.End)
+	}
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+// Returns how many integers are in the range, or the largest integer if there are even more than that
+func (r *Range) Len() int64 {
+	// The distance between Start and End can be more than the largest int64, so it's worked out in uint64, where it always fits
+	var span, step uint64
+	switch {
+	case r.Step > 0 && r.Start < r.End:
+		span, step = uint64(r.End)-uint64(r.Start), uint64(r.Step)
+	case r.Step < 0 && r.Start > r.End:
+		span, step = uint64(r.Start)-uint64(r.End), -uint64(r.Step)
+	default:
+		return 0
+	}
+	n := (span-1)/step + 1
+	// Only a range counting by 1 across nearly every integer has more integers than an int64 can count
+	if n > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(n)
+}
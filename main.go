@@ -1,10 +1,17 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 
+	"github.com/ajtroup1/interpreters/evaluation/evaluator"
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
+	"github.com/ajtroup1/interpreters/parsing/lexer"
+	"github.com/ajtroup1/interpreters/parsing/parser"
 	"github.com/ajtroup1/interpreters/repl"
 )
 
@@ -15,13 +22,105 @@ const (
 	Yellow  = "\033[33m"
 )
 
+// Exit codes for the clear binary
+const (
+	exitOK    = 0
+	exitError = 1 // the program couldn't be read, failed to parse or hit a runtime error
+	exitUsage = 2 // the command line itself was wrong
+)
+
+const usage = `Usage:
+  clear                 start the REPL
+  clear <file.clr>      run a Clear program
+  clear -               run a Clear program read from stdin
+  clear -e '<source>'   evaluate source code and print its value
+`
+
 func main() {
-	user, err := user.Current()
-	if err != nil {
-		panic(err)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+/*
+Runs the clear command with the given arguments and returns the exit code
+Kept separate from main so it can be driven with any input and output
+*/
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("clear", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	expression := flags.String("e", "", "evaluate source code and print its value")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	evaluating := false
+	flags.Visit(func(f *flag.Flag) { evaluating = evaluating || f.Name == "e" })
+
+	switch {
+	case evaluating:
+		if flags.NArg() != 0 {
+			flags.Usage()
+			return exitUsage
+		}
+		return execute(*expression, stdout, stderr, true)
+	case flags.NArg() == 0:
+		greet(stdout)
+		repl.Start(stdin, stdout, stderr)
+		return exitOK
+	case flags.NArg() == 1:
+		source, err := readSource(flags.Arg(0), stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "clear: %s\n", err)
+			return exitError
+		}
+		return execute(source, stdout, stderr, false)
+	default:
+		flags.Usage()
+		return exitUsage
+	}
+}
+
+func greet(out io.Writer) {
+	name := "there"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	fmt.Fprintf(out, "Hello %s! This is the Clear programming language!\n", name)
+	fmt.Fprintf(out, "Feel free to type in commands\n")
+}
+
+// Reads the program from the named file, or from stdin if the name is "-"
+func readSource(name string, stdin io.Reader) (string, error) {
+	var source []byte
+	var err error
+	if name == "-" {
+		source, err = io.ReadAll(stdin)
+	} else {
+		source, err = os.ReadFile(name)
+	}
+	return string(source), err
+}
+
+/*
+Parses and evaluates a whole program in a fresh environment
+Parse errors stop the program before anything runs, both they and runtime errors are rendered to stderr
+If printResult is true the program's final value is written to stdout, as long as it ends in an expression like it would in the REPL
+*/
+func execute(source string, stdout, stderr io.Writer, printResult bool) int {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) != 0 {
+		fmt.Fprint(stderr, diagnostic.RenderAll(source, diagnostics, false))
+		return exitError
+	}
+
+	result := evaluator.Eval(program, object.NewEnvironment())
+	if err, ok := result.(*object.Error); ok {
+		d := diagnostic.Diagnostic{Span: err.Span, Severity: diagnostic.Error, Code: diagnostic.RuntimeError, Message: err.Message}
+		fmt.Fprint(stderr, d.Render(source, false))
+		return exitError
+	}
+	if printResult && result != nil && program.EndsWithExpression() {
+		fmt.Fprintln(stdout, result.Inspect())
 	}
-	fmt.Printf("Hello %s! This is the Clear programming language!\n",
-		user.Username)
-	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	return exitOK
 }
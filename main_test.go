@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ajtroup1/interpreters/util"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, source string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ok := writeFile("ok.clr", "#!/usr/bin/env clear\nlet double = fn(x) { x * 2 };\ndouble(21);\n")
+	parseError := writeFile("parse.clr", "let x 5;\n")
+	runtimeError := writeFile("runtime.clr", "let x = 1;\nx + true;\n")
+
+	tests := []struct {
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string // only checked to be contained in stderr
+	}{
+		{[]string{ok}, "", exitOK, "", ""},
+		{[]string{parseError}, "", exitError, "", "error[P001]: expected next token to be =, got INT instead\n --> 1:7"},
+		{[]string{runtimeError}, "", exitError, "", "error[R001]: type mismatch: INTEGER + BOOLEAN\n --> 2:1"},
+		{[]string{filepath.Join(dir, "missing.clr")}, "", exitError, "", "missing.clr"},
+		{[]string{"-e", "1 + 2 * 3"}, "", exitOK, "7\n", ""},
+		{[]string{"-e", `"${1}${2}"`}, "", exitOK, "12\n", ""},
+		{[]string{"-e", "let x = 1"}, "", exitOK, "", ""},
+		{[]string{"-e", "for (i in range(2)) {}"}, "", exitOK, "", ""},
+		{[]string{"-e", "let x = 1; return x"}, "", exitOK, "1\n", ""},
+		{[]string{"-e", "1 +"}, "", exitError, "", "error[P002]"},
+		{[]string{"-"}, "let y = 2; y * y", exitOK, "", ""},
+		{[]string{"-"}, "#!/usr/bin/env clear\nmissing", exitError, "", "identifier not found: missing"},
+		{[]string{"-e", "1", ok}, "", exitUsage, "", "Usage:"},
+		{[]string{ok, ok}, "", exitUsage, "", "Usage:"},
+		{[]string{"-nope"}, "", exitUsage, "", "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+		if code != tt.expectedCode {
+			t.Errorf(util.RedText(fmt.Sprintf("%v - wrong exit code. expected=%d, got=%d (stderr=%q)", tt.args, tt.expectedCode, code, stderr.String())))
+		}
+		if stdout.String() != tt.expectedStdout {
+			t.Errorf(util.RedText(fmt.Sprintf("%v - wrong stdout. expected=%q, got=%q", tt.args, tt.expectedStdout, stdout.String())))
+		}
+		if !strings.Contains(stderr.String(), tt.expectedStderr) || (tt.expectedStderr == "" && stderr.Len() != 0) {
+			t.Errorf(util.RedText(fmt.Sprintf("%v - wrong stderr. expected to contain %q, got=%q", tt.args, tt.expectedStderr, stderr.String())))
+		}
+	}
+}
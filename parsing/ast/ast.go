@@ -9,6 +9,8 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
+	"strings"
 
 	"github.com/ajtroup1/interpreters/parsing/token"
 )
@@ -20,10 +22,12 @@ Each node is created differently since they serve much different purposes
 	Ex. a 'let' statement holds completely different values and keys than an expression statement
 
 However, every node must be able to provide a literal value for its token(s) and its respective type differentiator
+Every node also knows the span of source code it was parsed from, which the parser records in its Loc field
 */
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Span() token.Span
 }
 
 type Statement interface {
@@ -59,6 +63,29 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+// The Program spans from the start of its first statement to the end of its last
+func (p *Program) Span() token.Span {
+	if len(p.Statements) == 0 {
+		return token.Span{}
+	}
+	return token.Span{
+		Start: p.Statements[0].Span().Start,
+		End:   p.Statements[len(p.Statements)-1].Span().End,
+	}
+}
+
+// Returns whether the Program ends in an expression (or a return), the only kind of program with a final value worth showing, bindings and loops don't have one
+func (p *Program) EndsWithExpression() bool {
+	if len(p.Statements) == 0 {
+		return false
+	}
+	switch p.Statements[len(p.Statements)-1].(type) {
+	case *ExpressionStatement, *ReturnStatement:
+		return true
+	}
+	return false
+}
+
 // Returns the first literal value in the entire Program node, giving a rough description of the start of the program
 func (p *Program) TokenLiteral() string {
 	if len(p.Statements) > 0 {
@@ -71,10 +98,12 @@ func (p *Program) TokenLiteral() string {
 type ExpressionStatement struct {
 	Token      token.Token // the first token of the expression
 	Expression Expression
+	Loc        token.Span
 }
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Span() token.Span     { return es.Loc }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -82,14 +111,25 @@ func (es *ExpressionStatement) String() string {
 	return ""
 }
 
+/*
+Binds a name to a value, either with let or const
+A constant can't be assigned to afterwards, a let binding can
+	Ex. let x = 5; const limit = 10;
+*/
 type LetStatement struct {
-	Token token.Token // the token.LET token
+	Token token.Token // the token.LET or token.CONST token
 	Name  *Identifier
 	Value Expression
+	Loc   token.Span
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Span() token.Span     { return ls.Loc }
+
+// Returns whether the binding was declared with const
+func (ls *LetStatement) Constant() bool { return ls.Token.Type == token.CONST }
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
@@ -105,19 +145,23 @@ func (ls *LetStatement) String() string {
 type Identifier struct {
 	Token token.Token // the token.IDENT token
 	Value string
+	Loc   token.Span
 }
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
-func (i *Identifier) String() string { return i.Value }
+func (i *Identifier) Span() token.Span     { return i.Loc }
+func (i *Identifier) String() string       { return i.Value }
 
 type ReturnStatement struct {
 	Token       token.Token // the 'return' token
 	ReturnValue Expression
+	Loc         token.Span
 }
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Span() token.Span     { return rs.Loc }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(rs.TokenLiteral() + " ")
@@ -128,8 +172,496 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+/*
+A block is a list of statements wrapped in braces, like the body of a function
+	Ex. { let y = x * 2; return y; }
+*/
+type BlockStatement struct {
+	Token      token.Token // the '{' token
+	Statements []Statement
+	Loc        token.Span
+}
+
+func (bs *BlockStatement) statementNode()       {}
+func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Span() token.Span     { return bs.Loc }
+func (bs *BlockStatement) String() string {
+	var out bytes.Buffer
+	for _, s := range bs.Statements {
+		out.WriteString(s.String())
+	}
+	return out.String()
+}
+
+/*
+Runs its body for as long as the condition is truthy
+Any loop can have a label in front of it, which break and continue use to name it from inside a nested loop
+	Ex. while (i < 10) { i += 1; }
+	Ex. outer: while (true) { break outer; }
+*/
+type WhileStatement struct {
+	Token     token.Token // the 'while' token
+	Label     *Identifier // nil if the loop isn't labelled
+	Condition Expression
+	Body      *BlockStatement
+	Loc       token.Span
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Span() token.Span     { return ws.Loc }
+func (ws *WhileStatement) String() string {
+	return labelString(ws.Label) + "while " + ws.Condition.String() + " " + ws.Body.String()
+}
+
+/*
+A C-style loop, which runs Init once, then its body for as long as Condition is truthy, running Step after each pass
+Every part is optional, and a missing condition loops until something breaks out
+	Ex. for (let i = 0; i < 10; i += 1) { puts(i); }
+*/
+type ForStatement struct {
+	Token     token.Token // the 'for' token
+	Label     *Identifier // nil if the loop isn't labelled
+	Init      Statement   // nil if left out
+	Condition Expression  // nil if left out
+	Step      Expression  // nil if left out
+	Body      *BlockStatement
+	Loc       token.Span
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) Span() token.Span     { return fs.Loc }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(labelString(fs.Label) + "for (")
+	if fs.Init != nil {
+		out.WriteString(strings.TrimSuffix(fs.Init.String(), ";"))
+	}
+	out.WriteString("; ")
+	if fs.Condition != nil {
+		out.WriteString(fs.Condition.String())
+	}
+	out.WriteString("; ")
+	if fs.Step != nil {
+		out.WriteString(fs.Step.String())
+	}
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+	return out.String()
+}
+
+/*
+Runs its body once for each item of a collection, with Variable bound to the item
+Arrays give their elements, hashes their keys, strings their characters and ranges their integers
+	Ex. for (x in [1, 2, 3]) { puts(x); }
+*/
+type ForInStatement struct {
+	Token    token.Token // the 'for' token
+	Label    *Identifier // nil if the loop isn't labelled
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+	Loc      token.Span
+}
+
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) Span() token.Span     { return fs.Loc }
+func (fs *ForInStatement) String() string {
+	return labelString(fs.Label) + "for (" + fs.Variable.String() + " in " + fs.Iterable.String() + ") " + fs.Body.String()
+}
+
+/*
+Stops the innermost loop, or the loop with the given label
+	Ex. break; break outer;
+*/
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+	Label *Identifier // nil if no label was given
+	Loc   token.Span
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Span() token.Span     { return bs.Loc }
+func (bs *BreakStatement) String() string {
+	if bs.Label != nil {
+		return "break " + bs.Label.String() + ";"
+	}
+	return "break;"
+}
+
+/*
+Skips the rest of the body of the innermost loop, or the loop with the given label, moving on to its next pass
+	Ex. continue; continue outer;
+*/
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+	Label *Identifier // nil if no label was given
+	Loc   token.Span
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Span() token.Span     { return cs.Loc }
+func (cs *ContinueStatement) String() string {
+	if cs.Label != nil {
+		return "continue " + cs.Label.String() + ";"
+	}
+	return "continue;"
+}
+
+func labelString(label *Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.String() + ": "
+}
+
 /*
 	---------------------------------------------------------------------------------------------------------------------
 	**ALL EXPRESSIONS**     **ALL EXPRESSIONS**     **ALL EXPRESSIONS**     **ALL EXPRESSIONS**
 	---------------------------------------------------------------------------------------------------------------------
 */
+
+/*
+Integers that fit in 64 bits are stored in Value
+Anything bigger is kept as an arbitrary-precision Big instead, which is nil otherwise
+*/
+type IntegerLiteral struct {
+	Token token.Token // the token.INT token
+	Value int64
+	Big   *big.Int
+	Loc   token.Span
+}
+
+func (il *IntegerLiteral) expressionNode()      {}
+func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Span() token.Span     { return il.Loc }
+func (il *IntegerLiteral) String() string       { return il.Token.Literal }
+
+type FloatLiteral struct {
+	Token token.Token // the token.FLOAT token
+	Value float64
+	Loc   token.Span
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Span() token.Span     { return fl.Loc }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
+/*
+A prefix expression is an operator placed in front of a single operand
+
+	Ex. !isValid, -5
+*/
+type PrefixExpression struct {
+	Token    token.Token // the prefix token, e.g. ! or -
+	Operator string
+	Right    Expression
+	Loc      token.Span
+}
+
+func (pe *PrefixExpression) expressionNode()      {}
+func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Span() token.Span     { return pe.Loc }
+func (pe *PrefixExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(pe.Operator)
+	out.WriteString(pe.Right.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+/*
+An infix expression is an operator placed between two operands
+
+	Ex. 5 + 5, x == y
+
+String() wraps the expression in parentheses so the structure the parser decided on is visible
+*/
+type InfixExpression struct {
+	Token    token.Token // the operator token, e.g. +
+	Left     Expression
+	Operator string
+	Right    Expression
+	Loc      token.Span
+}
+
+func (ie *InfixExpression) expressionNode()      {}
+func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Span() token.Span     { return ie.Loc }
+func (ie *InfixExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString(" " + ie.Operator + " ")
+	out.WriteString(ie.Right.String())
+	out.WriteString(")")
+	return out.String()
+}
+
+type Boolean struct {
+	Token token.Token // the token.TRUE or token.FALSE token
+	Value bool
+	Loc   token.Span
+}
+
+func (b *Boolean) expressionNode()      {}
+func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Span() token.Span     { return b.Loc }
+func (b *Boolean) String() string       { return b.Token.Literal }
+
+type StringLiteral struct {
+	Token token.Token // the token.STRING token
+	Value string      // the contents with every escape sequence resolved
+	Loc   token.Span
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Span() token.Span     { return sl.Loc }
+func (sl *StringLiteral) String() string       { return `"` + escapeString(sl.Value) + `"` }
+
+/*
+A string containing "${...}" interpolations
+Parts alternate between StringLiterals for the plain text and the expressions that were interpolated
+	Ex. "hello ${name}!" = [ StringLiteral("hello "), Identifier(name), StringLiteral("!") ]
+*/
+type InterpolatedString struct {
+	Token token.Token // the token.STRING token
+	Parts []Expression
+	Loc   token.Span
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Span() token.Span     { return is.Loc }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteString(`"`)
+	for _, part := range is.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(escapeString(text.Value))
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString(`"`)
+	return out.String()
+}
+
+// Writes string contents back out the way they'd have to be typed in Clear source
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`, "${", `\${`)
+
+func escapeString(s string) string {
+	return stringEscaper.Replace(s)
+}
+
+/*
+In Clear, if/else is an expression, so it produces a value just like 5 + 5 does
+	Ex. let max = if (a > b) { a } else { b };
+An 'else if' is stored as an Alternative block holding a single nested IfExpression
+*/
+type IfExpression struct {
+	Token       token.Token // the 'if' token
+	Condition   Expression
+	Consequence *BlockStatement
+	Alternative *BlockStatement // nil when there is no else branch
+	Loc         token.Span
+}
+
+func (ie *IfExpression) expressionNode()      {}
+func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Span() token.Span     { return ie.Loc }
+func (ie *IfExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("if ")
+	out.WriteString(ie.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ie.Consequence.String())
+	if ie.Alternative != nil {
+		out.WriteString(" else ")
+		out.WriteString(ie.Alternative.String())
+	}
+	return out.String()
+}
+
+/*
+A function literal defines a function value, which can be bound to a name like any other value
+	Ex. fn(x, y) { x + y; }
+*/
+type FunctionLiteral struct {
+	Token      token.Token // the 'fn' token
+	Parameters []*Identifier
+	Body       *BlockStatement
+	Loc        token.Span
+}
+
+func (fl *FunctionLiteral) expressionNode()      {}
+func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Span() token.Span     { return fl.Loc }
+func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range fl.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(fl.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(fl.Body.String())
+	return out.String()
+}
+
+/*
+A call expression applies a function to a list of arguments
+The function can be any expression that evaluates to a function, not just an identifier
+	Ex. add(1, 2), fn(x) { x; }(5), makeAdder(1)(2)
+*/
+type CallExpression struct {
+	Token     token.Token // the '(' token
+	Function  Expression  // Identifier or FunctionLiteral
+	Arguments []Expression
+	Loc       token.Span
+}
+
+func (ce *CallExpression) expressionNode()      {}
+func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Span() token.Span     { return ce.Loc }
+func (ce *CallExpression) String() string {
+	var out bytes.Buffer
+	args := []string{}
+	for _, a := range ce.Arguments {
+		args = append(args, a.String())
+	}
+	out.WriteString(ce.Function.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(")")
+	return out.String()
+}
+
+/*
+An array literal creates a new array holding the value of each element, in order
+	Ex. [1, 2 * 2, "three"]
+*/
+type ArrayLiteral struct {
+	Token    token.Token // the '[' token
+	Elements []Expression
+	Loc      token.Span
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Span() token.Span     { return al.Loc }
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+/*
+An index expression reads a single element out of a collection
+	Ex. myArray[0], myArray[-1], "abc"[1]
+*/
+type IndexExpression struct {
+	Token token.Token // the '[' token
+	Left  Expression
+	Index Expression
+	Loc   token.Span
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Span() token.Span     { return ie.Loc }
+func (ie *IndexExpression) String() string {
+	return "(" + ie.Left.String() + "[" + ie.Index.String() + "])"
+}
+
+/*
+A slice expression copies the elements of a collection from Low up to (but not including) High
+Either bound can be left out, meaning the start or the end of the collection
+	Ex. myArray[1:3], myArray[:2], myArray[1:]
+*/
+type SliceExpression struct {
+	Token token.Token // the '[' token
+	Left  Expression
+	Low   Expression // nil if left out
+	High  Expression // nil if left out
+	Loc   token.Span
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) Span() token.Span     { return se.Loc }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(" + se.Left.String() + "[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
+/*
+A hash literal creates a new hash holding each key and value pair, in order
+	Ex. {"name": "Clear", 1: true}
+*/
+type HashLiteral struct {
+	Token token.Token // the '{' token
+	Pairs []HashPair
+	Loc   token.Span
+}
+
+// One key: value pair of a hash literal
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Span() token.Span     { return hl.Loc }
+func (hl *HashLiteral) String() string {
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+/*
+An assignment stores a new value in the place its target names, and evaluates to that value
+A compound assignment like += applies its operator to the current value and the new one first
+	Ex. x = x + 1, myHash["key"] = 5, arr[0] += 2
+*/
+type AssignExpression struct {
+	Token  token.Token // the '=' token, or a compound one like '+='
+	Target Expression  // an Identifier or IndexExpression
+	Value  Expression
+	Loc    token.Span
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Span() token.Span     { return ae.Loc }
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " " + ae.Token.Literal + " " + ae.Value.String() + ")"
+}
+
+// Returns the operator a compound assignment applies, ex. "+" for "+=", or "" for a plain '='
+func (ae *AssignExpression) Operator() string {
+	return strings.TrimSuffix(ae.Token.Literal, "=")
+}
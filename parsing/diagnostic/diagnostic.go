@@ -0,0 +1,176 @@
+/*
+diagnostic
+Structured errors and warnings reported by the lexer and parser
+A diagnostic knows exactly which span of source code it's about, so it can be rendered with the offending line underlined
+*/
+package diagnostic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ajtroup1/interpreters/parsing/token"
+	"github.com/ajtroup1/interpreters/util"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+/*
+Codes uniquely identify each kind of problem so they can be looked up, tested for and silenced without matching on messages
+Codes starting with L come from the lexer, codes starting with P come from the parser and codes starting with R come from evaluating the program
+*/
+type Code string
+
+const (
+	IllegalCharacter    Code = "L001"
+	UnterminatedString  Code = "L002"
+	InvalidEscape       Code = "L003"
+	MalformedNumber     Code = "L004"
+	UnterminatedComment Code = "L005"
+	InvalidUTF8         Code = "L006"
+
+	UnexpectedToken      Code = "P001"
+	ExpectedExpression   Code = "P002"
+	InvalidInteger       Code = "P003"
+	InvalidInterpolation Code = "P004"
+	InvalidFloat         Code = "P005"
+	InvalidAssignment    Code = "P006"
+	ConstantAssignment   Code = "P007"
+	MisplacedLoopControl Code = "P008"
+	UnknownLabel         Code = "P009"
+
+	RuntimeError Code = "R001"
+)
+
+/*
+A single problem found in the source code
+
+	Ex. Diagnostic = { Span: 1:7-1:8, Severity: Error, Code: P001, Message: "expected next token to be =, got INT instead" }
+
+Notes add extra context and Fix optionally suggests how to resolve the problem
+*/
+type Diagnostic struct {
+	Span     token.Span
+	Severity Severity
+	Code     Code
+	Message  string
+	Notes    []string
+	Fix      string
+}
+
+// Diagnostics can be used as plain Go errors, formatted on a single line
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s[%s]: %s", d.Span.Start, d.Severity, d.Code, d.Message)
+}
+
+/*
+Renders the diagnostic along with the line of source code it points at, underlining the span with carets
+
+	Ex.
+	error[P001]: expected next token to be =, got INT instead
+	 --> 1:7
+	  |
+	1 | let x 5;
+	  |       ^
+	  = help: insert '=' here
+
+If color is true, the header and underline are colored using the util color helpers
+*/
+func (d Diagnostic) Render(source string, color bool) string {
+	paint := func(colorize func(string) string, text string) string {
+		if color {
+			return colorize(text)
+		}
+		return text
+	}
+	severityColor := util.RedText
+	if d.Severity != Error {
+		severityColor = util.YellowText
+	}
+
+	lineNumber := strconv.Itoa(d.Span.Start.Line)
+	gutter := strings.Repeat(" ", len(lineNumber))
+	line := sourceLine(source, d.Span.Start.Line)
+
+	var out strings.Builder
+	out.WriteString(paint(severityColor, fmt.Sprintf("%s[%s]", d.Severity, d.Code)))
+	out.WriteString(": " + d.Message + "\n")
+	out.WriteString(fmt.Sprintf("%s--> %s\n", gutter, d.Span.Start))
+	out.WriteString(gutter + " |\n")
+	out.WriteString(lineNumber + " | " + line + "\n")
+	out.WriteString(gutter + " | " + underlinePadding(line, d.Span.Start.Column))
+	out.WriteString(paint(severityColor, strings.Repeat("^", underlineWidth(line, d.Span))) + "\n")
+	for _, note := range d.Notes {
+		out.WriteString(gutter + " = note: " + note + "\n")
+	}
+	if d.Fix != "" {
+		out.WriteString(gutter + " = " + paint(util.GreenText, "help") + ": " + d.Fix + "\n")
+	}
+	return out.String()
+}
+
+// Renders every diagnostic against the same source, separated by blank lines
+func RenderAll(source string, diagnostics []Diagnostic, color bool) string {
+	rendered := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		rendered[i] = d.Render(source, color)
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// Returns the given 1-based line of the source, without its line ending
+func sourceLine(source string, line int) string {
+	lines := strings.Split(source, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimRight(lines[line-1], "\r")
+}
+
+// Whitespace to put in front of the carets so they line up with the column, keeping tabs so they line up the same way
+func underlinePadding(line string, column int) string {
+	var padding strings.Builder
+	for i, ch := range []rune(line) {
+		if i >= column-1 {
+			break
+		}
+		if ch == '\t' {
+			padding.WriteByte('\t')
+		} else {
+			padding.WriteByte(' ')
+		}
+	}
+	return padding.String()
+}
+
+/*
+Number of carets needed to underline the span
+Spans that continue onto later lines are underlined to the end of the first line
+Empty spans (like the EOF token) still get a single caret so there is always something to point at
+*/
+func underlineWidth(line string, span token.Span) int {
+	width := span.End.Column - span.Start.Column
+	if span.End.Line != span.Start.Line {
+		width = utf8.RuneCountInString(line) - (span.Start.Column - 1)
+	}
+	return max(width, 1)
+}
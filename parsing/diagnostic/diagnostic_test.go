@@ -0,0 +1,91 @@
+package diagnostic
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ajtroup1/interpreters/parsing/token"
+	"github.com/ajtroup1/interpreters/util"
+)
+
+func TestRender(t *testing.T) {
+	source := "let x = 5;\nlet y 10;"
+	d := Diagnostic{
+		Span: token.Span{
+			Start: token.Position{Line: 2, Column: 7, Offset: 17},
+			End:   token.Position{Line: 2, Column: 9, Offset: 19},
+		},
+		Severity: Error,
+		Code:     UnexpectedToken,
+		Message:  "expected next token to be =, got INT instead",
+		Notes:    []string{"let statements need a value"},
+		Fix:      `insert "=" after "y"`,
+	}
+
+	expected := `error[P001]: expected next token to be =, got INT instead
+ --> 2:7
+  |
+2 | let y 10;
+  |       ^^
+  = note: let statements need a value
+  = help: insert "=" after "y"
+`
+	if actual := d.Render(source, false); actual != expected {
+		t.Errorf(util.RedText(fmt.Sprintf("Render() wrong.\nexpected=\n%s\ngot=\n%s", expected, actual)))
+	}
+
+	colored := d.Render(source, true)
+	if colored == expected {
+		t.Errorf(util.RedText("Render() with color did not add any color codes"))
+	}
+}
+
+func TestRenderKeepsTabsAndPointsAtEOF(t *testing.T) {
+	source := "\tlet x ="
+	eof := token.Position{Line: 1, Column: 9, Offset: 8}
+	d := Diagnostic{Span: token.Span{Start: eof, End: eof}, Code: ExpectedExpression, Message: "no prefix parse function for EOF found"}
+
+	expected := `error[P002]: no prefix parse function for EOF found
+ --> 1:9
+  |
+1 | 	let x =
+  | 	       ^
+`
+	if actual := d.Render(source, false); actual != expected {
+		t.Errorf(util.RedText(fmt.Sprintf("Render() wrong.\nexpected=\n%s\ngot=\n%s", expected, actual)))
+	}
+}
+
+func TestRenderCountsCharacters(t *testing.T) {
+	source := `let s = "héllo" + ünknown;`
+	d := Diagnostic{
+		Span: token.Span{
+			Start: token.Position{Line: 1, Column: 19, Offset: 20},
+			End:   token.Position{Line: 1, Column: 26, Offset: 28},
+		},
+		Code:    RuntimeError,
+		Message: "identifier not found: ünknown",
+	}
+
+	expected := `error[R001]: identifier not found: ünknown
+ --> 1:19
+  |
+1 | let s = "héllo" + ünknown;
+  |                   ^^^^^^^
+`
+	if actual := d.Render(source, false); actual != expected {
+		t.Errorf(util.RedText(fmt.Sprintf("Render() wrong.\nexpected=\n%s\ngot=\n%s", expected, actual)))
+	}
+}
+
+func TestError(t *testing.T) {
+	d := Diagnostic{
+		Span:     token.Span{Start: token.Position{Line: 3, Column: 4}},
+		Severity: Warning,
+		Code:     IllegalCharacter,
+		Message:  "unexpected character",
+	}
+	if d.Error() != "3:4: warning[L001]: unexpected character" {
+		t.Errorf(util.RedText(fmt.Sprintf("Error() wrong. got=%q", d.Error())))
+	}
+}
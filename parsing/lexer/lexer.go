@@ -1,19 +1,40 @@
 package lexer
 
-import "github.com/ajtroup1/interpreters/parsing/token"
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
+	"github.com/ajtroup1/interpreters/parsing/token"
+)
 
 /*
 	Overall state and structure for the Lexer for Clear
 	The Lexer processes source code char by char and forms a string of tokens to be parsed
 	The Lexer recieves raw input text, processes the chars individually, and makes decisions based on the read char
+	Source code is UTF-8, and a char is a whole Unicode code point (a rune), not a single byte
 	Clear's Lexer does not consider whitespace
 		Why does Python's consider whitespace?
 */
 type Lexer struct {
 	input        string
-	position     int  // current position in input (points to current char)
-	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	position     int            // current byte position in input (points to current char)
+	readPosition int            // current reading byte position in input (after current char)
+	ch           rune           // current char under examination
+	line         int            // line of the current char, starting at 1
+	column       int            // column of the current char in runes, starting at 1
+	base         token.Position // where the input starts in the original source, see NewAt
+	keepTrivia   bool           // whether whitespace and comments are attached to tokens, see NewWithTrivia
+	diagnostics  []diagnostic.Diagnostic
+
+	// Only used when the source is streamed, see NewReader
+	reader    io.Reader
+	buf       []byte // scratch space for reading the next chunk
+	discarded int    // bytes dropped from the front of input since they were lexed
+	readErr   error  // why the reader stopped, io.EOF once the whole source has been read
 }
 
 /*
@@ -21,43 +42,147 @@ type Lexer struct {
 	Recieves the entire source code to assign to the Lexer state
 */
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewAt(input, token.Position{Line: 1, Column: 1, Offset: 0})
+}
+
+/*
+Instantiates a Lexer that attaches whitespace and comments to the tokens around them as trivia
+Concatenating every token's leading trivia, source text and trailing trivia gives back the input exactly
+*/
+func NewWithTrivia(input string) *Lexer {
+	l := New(input)
+	l.keepTrivia = true
+	return l
+}
+
+/*
+Instantiates a Lexer for input that is a piece of some larger source, starting at the given position within it
+Every position the Lexer reports is relative to the larger source
+	Ex. the parser uses this to lex the expression inside a string interpolation
+*/
+func NewAt(input string, base token.Position) *Lexer {
+	l := &Lexer{input: input, line: 1, base: base}
 	l.readChar()
 	return l
 }
 
 // Simple (but crucial) helper function to either return the current char, update the Lexer state, and check for EOF
+// Moving past a newline starts a new line, so positions always describe the char now in l.ch
+// A byte that isn't valid UTF-8 is reported and becomes utf8.RuneError, one byte wide
 func (l *Lexer) readChar() {
+	l.fill(l.readPosition + utf8.UTFMax)
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	if l.readPosition <= len(l.input) {
+		l.column++
+	}
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
+	if l.invalidChar() {
+		start := l.pos()
+		end := token.Position{Line: start.Line, Column: start.Column + 1, Offset: start.Offset + 1}
+		l.errorIn(token.Span{Start: start, End: end}, diagnostic.InvalidUTF8, fmt.Sprintf("invalid UTF-8 byte %#x", l.input[l.position]))
+	}
+}
+
+// Returns whether the current char is a byte that isn't valid UTF-8, rather than a real U+FFFD written in the source
+func (l *Lexer) invalidChar() bool {
+	return l.ch == utf8.RuneError && l.readPosition-l.position == 1
+}
+
+// Returns every problem the Lexer has run into so far
+func (l *Lexer) Diagnostics() []diagnostic.Diagnostic {
+	return l.diagnostics
+}
+
+/*
+Returns the source code the Lexer was created with, so diagnostics can be rendered against it
+A Lexer created with NewReader doesn't hold on to source it has already lexed, so only the part still buffered is returned
+*/
+func (l *Lexer) Input() string {
+	return l.input
 }
 
 // Returns the char located a l.readPosition in the source code. Does not advance the Lexer state
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
+	l.fill(l.readPosition + utf8.UTFMax)
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return r
 	}
 }
 
+// Returns the position of the current char
+// Once the input is exhausted every position points just past its end
+func (l *Lexer) pos() token.Position {
+	column := l.column
+	if l.line == 1 {
+		column += l.base.Column - 1
+	}
+	return token.Position{
+		Line:   l.base.Line + l.line - 1,
+		Column: column,
+		Offset: l.base.Offset + l.discarded + min(l.position, len(l.input)),
+	}
+}
+
+// Records an error diagnostic covering the given span
+func (l *Lexer) errorIn(span token.Span, code diagnostic.Code, msg string) {
+	l.diagnostics = append(l.diagnostics, diagnostic.Diagnostic{
+		Span:     span,
+		Severity: diagnostic.Error,
+		Code:     code,
+		Message:  msg,
+	})
+}
+
+// Records an error diagnostic spanning from start up to the current char
+func (l *Lexer) errorFrom(start token.Position, code diagnostic.Code, msg string) {
+	l.errorIn(token.Span{Start: start, End: l.pos()}, code, msg)
+}
+
 /*
 	The core decision-making function of the Lexer to be called repeatedly
+	Returns the next token in the source, along with where it starts and ends
+*/
+func (l *Lexer) NextToken() token.Token {
+	l.discard()
+
+	// Clear does not consider whitespace or comments
+	leading := l.readTrivia(false)
+
+	start := l.pos()
+	tok := l.readToken()
+	tok.Start = start
+	tok.End = l.pos()
+
+	if l.keepTrivia {
+		tok.Leading = leading
+		if tok.Type != token.EOF {
+			tok.Trailing = l.readTrivia(true)
+		}
+	}
+	return tok
+}
+
+/*
 	Reads the current char and returns its corresponding token
 	If no 'direct' match is found, the result is read as an identifier or integer
 		If it does not match either of these conditions, it is an illegal character
 */
-func (l *Lexer) NextToken() token.Token {
+func (l *Lexer) readToken() token.Token {
 	var tok token.Token
 
-	// Clear does not consider whitespace
-	l.skipWhitespace()
-
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -68,9 +193,9 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.followedBy('=', token.PLUS_ASSIGN, token.PLUS)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		tok = l.followedBy('=', token.MINUS_ASSIGN, token.MINUS)
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -80,13 +205,35 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.followedBy('=', token.SLASH_ASSIGN, token.SLASH)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			tok = l.followedBy('*', token.POWER, token.ASTERISK)
+		} else {
+			tok = l.followedBy('=', token.ASTERISK_ASSIGN, token.ASTERISK)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '<' {
+			tok = l.followedBy('<', token.SHIFT_LEFT, token.LT)
+		} else {
+			tok = l.followedBy('=', token.LT_EQ, token.LT)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '>' {
+			tok = l.followedBy('>', token.SHIFT_RIGHT, token.GT)
+		} else {
+			tok = l.followedBy('=', token.GT_EQ, token.GT)
+		}
+	case '&':
+		tok = l.followedBy('&', token.AND, token.AMPERSAND)
+	case '|':
+		tok = l.followedBy('|', token.OR, token.PIPE)
+	case '^':
+		tok = newToken(token.CARET, l.ch)
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case ';':
@@ -99,6 +246,14 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
+	case '"':
+		return l.readString()
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -108,11 +263,9 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
-			return tok
+			return l.readNumber()
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			return l.readIllegal()
 		}
 
 	}
@@ -120,8 +273,79 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+// Returns the two-char token if the current char is followed by next, otherwise the single-char token
+func (l *Lexer) followedBy(next rune, double, single token.TokenType) token.Token {
+	if l.peekChar() != next {
+		return newToken(single, l.ch)
+	}
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: double, Literal: string(ch) + string(l.ch)}
+}
+
+/*
+Reads a string literal, starting on its opening quote and finishing just past its closing quote
+The token's literal is the raw text between the quotes, escapes and interpolations are resolved by the parser using Segments
+A string that is never closed becomes an ILLEGAL token running to the end of the input, and so does a string with a malformed escape
+*/
+func (l *Lexer) readString() token.Token {
+	start := l.pos()
+	end, terminated := scanString(l.input, l.readPosition)
+	// A streamed string may continue past what has been read so far
+	for !terminated && l.load() {
+		end, terminated = scanString(l.input, l.readPosition)
+	}
+	raw := l.input[l.readPosition:end]
+
+	contentStart := start.Advance(`"`)
+	_, errors := Segments(raw)
+	for _, err := range errors {
+		errStart := contentStart.Advance(raw[:err.Offset])
+		errEnd := errStart.Advance(raw[err.Offset : err.Offset+err.Length])
+		l.errorIn(token.Span{Start: errStart, End: errEnd}, diagnostic.InvalidEscape, err.Message)
+	}
+
+	for l.position < end {
+		l.readChar()
+	}
+	if !terminated {
+		l.errorFrom(start, diagnostic.UnterminatedString, "unterminated string")
+		return token.Token{Type: token.ILLEGAL, Literal: `"` + raw, Reason: token.UNTERMINATED_STRING}
+	}
+	l.readChar()
+	if len(errors) != 0 {
+		return token.Token{Type: token.ILLEGAL, Literal: `"` + raw + `"`, Reason: token.INVALID_ESCAPE}
+	}
+	return token.Token{Type: token.STRING, Literal: raw}
+}
+
+/*
+Reads a run of characters that can't start any token into a single ILLEGAL token, so "@@@" is one mistake rather than three
+Bytes that aren't valid UTF-8 are part of the run, but they are already reported by readChar
+*/
+func (l *Lexer) readIllegal() token.Token {
+	start := l.pos()
+	position := l.position
+	valid := 0
+	for !startsToken(l.ch) {
+		if !l.invalidChar() {
+			valid++
+		}
+		l.readChar()
+	}
+
+	literal := l.input[position:l.position]
+	switch {
+	case valid == 1 && utf8.RuneCountInString(literal) == 1:
+		l.errorFrom(start, diagnostic.IllegalCharacter, fmt.Sprintf("unexpected character %q", literal))
+	case valid > 0:
+		l.errorFrom(start, diagnostic.IllegalCharacter, fmt.Sprintf("unexpected characters %q", literal))
+	}
+	return token.Token{Type: token.ILLEGAL, Literal: literal, Reason: token.UNEXPECTED_CHARACTER}
+}
+
 // Helper function to abstract creating a token object
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
@@ -134,37 +358,33 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-// Returns whether the char is the in alphabet (upper or lower) or '_' (bool)
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+/*
+	Returns whether the char can start an identifier: any Unicode letter, or '_'
+	This is the same rule Go uses, so names like café, π and 变量 are all valid
+*/
+func isLetter(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch)
 }
 
 /*
-	Returns true if the character is in the alphabet (upper or lower)
-	Also returns true if the character is a digit (0 -> 9)
+	Returns true if the character can start an identifier (see isLetter)
+	Also returns true for any Unicode digit, combining mark or connector, following the Unicode identifier rules (UAX #31)
 	Since identifiers cannot begin with a number, but can continue with a nunmber, a separate check is needed than isLetter()
 */
-func isAlphanumeric(ch byte) bool {
-	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9') || ch == '_'
+func isAlphanumeric(ch rune) bool {
+	return isLetter(ch) || unicode.In(ch, unicode.Nd, unicode.Mn, unicode.Mc, unicode.Pc)
 }
 
-// Simple function to remove any whitespace character in front of the Lexer until a non-whitespace character is encountered
-func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		l.readChar()
-	}
+// Returns whether the char can start a token, or the whitespace and comments between them, see readToken
+func startsToken(ch rune) bool {
+	return ch == 0 || isWhitespace(ch) || isLetter(ch) || isDigit(ch) || strings.ContainsRune(`=+-!/*%<>&|^~,;(){}[]:"`, ch)
 }
 
-// Tracks character positions until a non-digit is encountered
-func (l *Lexer) readNumber() string {
-	position := l.position
-	for isDigit(l.ch) {
-		l.readChar()
-	}
-	return l.input[position:l.position]
+func isWhitespace(ch rune) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
 
-// Returns true if the character is a digit
-func isDigit(ch byte) bool {
+// Returns true if the character is a digit, only 0-9 count since they are the only digits number literals use
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
@@ -1,10 +1,17 @@
 package lexer
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
 	"github.com/ajtroup1/interpreters/parsing/token"
 	"github.com/ajtroup1/interpreters/util"
 )
@@ -16,7 +23,7 @@ func TestNextToken(t *testing.T) {
 			x + y;
 		};
 		let result = add(five, ten);
-		!-/*5;
+		!-/ *5;
 		5 < 10 > 5;
 		if (5 < 10) {
 			return true;
@@ -25,6 +32,10 @@ func TestNextToken(t *testing.T) {
 		}
 		10 == 10;
 		10 != 9;
+		[1, 2][0:1];
+		const c = 1; c += 2 -= 3 *= 4 /= 5;
+		outer: while for (i in x) break continue
+		<= >= && || % ** & | ^ ~ << >> *=
 	`
 
 	tests := []struct {
@@ -113,6 +124,60 @@ func TestNextToken(t *testing.T) {
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
 
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+
+		{token.CONST, "const"},
+		{token.IDENT, "c"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "c"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "2"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "3"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "4"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+
+		{token.IDENT, "outer"},
+		{token.COLON, ":"},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "i"},
+		{token.IN, "in"},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+
+		{token.LT_EQ, "<="},
+		{token.GT_EQ, ">="},
+		{token.AND, "&&"},
+		{token.OR, "||"},
+		{token.PERCENT, "%"},
+		{token.POWER, "**"},
+		{token.AMPERSAND, "&"},
+		{token.PIPE, "|"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.ASTERISK_ASSIGN, "*="},
+
 		{token.EOF, ""},
 	}
 
@@ -142,3 +207,668 @@ func TestNextToken(t *testing.T) {
 		log.Println(util.RedText(fmt.Sprintf("%d / %d LEXING TESTS PASSED", numPassed, len(tests))))
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 10;\n  x == y"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedStart   token.Position
+		expectedEnd     token.Position
+	}{
+		{"let", token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 4, Offset: 3}},
+		{"x", token.Position{Line: 1, Column: 5, Offset: 4}, token.Position{Line: 1, Column: 6, Offset: 5}},
+		{"=", token.Position{Line: 1, Column: 7, Offset: 6}, token.Position{Line: 1, Column: 8, Offset: 7}},
+		{"10", token.Position{Line: 1, Column: 9, Offset: 8}, token.Position{Line: 1, Column: 11, Offset: 10}},
+		{";", token.Position{Line: 1, Column: 11, Offset: 10}, token.Position{Line: 1, Column: 12, Offset: 11}},
+		{"x", token.Position{Line: 2, Column: 3, Offset: 14}, token.Position{Line: 2, Column: 4, Offset: 15}},
+		{"==", token.Position{Line: 2, Column: 5, Offset: 16}, token.Position{Line: 2, Column: 7, Offset: 18}},
+		{"y", token.Position{Line: 2, Column: 8, Offset: 19}, token.Position{Line: 2, Column: 9, Offset: 20}},
+		{"", token.Position{Line: 2, Column: 9, Offset: 20}, token.Position{Line: 2, Column: 9, Offset: 20}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf(util.RedText(fmt.Sprintf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)))
+		}
+		if tok.Start != tt.expectedStart || tok.End != tt.expectedEnd {
+			t.Errorf(util.RedText(fmt.Sprintf("tests[%d] - %q span wrong. expected=%+v..%+v, got=%+v..%+v",
+				i, tok.Literal, tt.expectedStart, tt.expectedEnd, tok.Start, tok.End)))
+		}
+	}
+
+	// Asking for more tokens after EOF must not move the position
+	if tok := l.NextToken(); tok.Type != token.EOF || tok.Start.Offset != len(input) {
+		t.Errorf(util.RedText(fmt.Sprintf("repeated EOF moved. got=%+v", tok)))
+	}
+}
+
+// Columns count characters, while offsets still count bytes
+func TestUnicodePositions(t *testing.T) {
+	input := "let café = \"héllo\";\nπ"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedStart   token.Position
+		expectedEnd     token.Position
+	}{
+		{token.LET, "let", token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 4, Offset: 3}},
+		{token.IDENT, "café", token.Position{Line: 1, Column: 5, Offset: 4}, token.Position{Line: 1, Column: 9, Offset: 9}},
+		{token.ASSIGN, "=", token.Position{Line: 1, Column: 10, Offset: 10}, token.Position{Line: 1, Column: 11, Offset: 11}},
+		{token.STRING, "héllo", token.Position{Line: 1, Column: 12, Offset: 12}, token.Position{Line: 1, Column: 19, Offset: 20}},
+		{token.SEMICOLON, ";", token.Position{Line: 1, Column: 19, Offset: 20}, token.Position{Line: 1, Column: 20, Offset: 21}},
+		{token.IDENT, "π", token.Position{Line: 2, Column: 1, Offset: 22}, token.Position{Line: 2, Column: 2, Offset: 24}},
+		{token.EOF, "", token.Position{Line: 2, Column: 2, Offset: 24}, token.Position{Line: 2, Column: 2, Offset: 24}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(util.RedText(fmt.Sprintf("tests[%d] - wrong token. expected=%s %q, got=%s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)))
+		}
+		if tok.Start != tt.expectedStart || tok.End != tt.expectedEnd {
+			t.Errorf(util.RedText(fmt.Sprintf("tests[%d] - %q span wrong. expected=%+v..%+v, got=%+v..%+v",
+				i, tok.Literal, tt.expectedStart, tt.expectedEnd, tok.Start, tok.End)))
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType token.TokenType
+		expected     string // the literal of the first token
+	}{
+		{"café", token.IDENT, "café"},
+		{"変数 + 1", token.IDENT, "変数"},
+		{"Δx", token.IDENT, "Δx"},
+		{"_ñ1", token.IDENT, "_ñ1"},
+		{"x١٢", token.IDENT, "x١٢"},                // Arabic-Indic digits can continue a name
+		{"cafe\u0301!", token.IDENT, "cafe\u0301"}, // so can a combining accent
+		{"a‿b", token.IDENT, "a‿b"},                // and a connector like the undertie
+		{"١٢", token.ILLEGAL, "١٢"},                // but none of them can start one
+		{"€", token.ILLEGAL, "€"},
+		{"😀", token.ILLEGAL, "😀"},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong token. expected=%s %q, got=%s %q", tt.input, tt.expectedType, tt.expected, tok.Type, tok.Literal)))
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedTypes  []token.TokenType
+		expectedStarts []string // where each invalid byte is reported
+	}{
+		{"x \xff y", []token.TokenType{token.IDENT, token.ILLEGAL, token.IDENT}, []string{"1:3"}},
+		{"é\xc3", []token.TokenType{token.IDENT, token.ILLEGAL}, []string{"1:2"}},
+		{"\"a\xffb\"", []token.TokenType{token.STRING}, []string{"1:3"}},
+		{"// \xfe\xfe\n1", []token.TokenType{token.INT}, []string{"1:4", "1:5"}},
+		{"\"\uFFFD\"", []token.TokenType{token.STRING}, nil},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		var types []token.TokenType
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			types = append(types, tok.Type)
+		}
+		if fmt.Sprint(types) != fmt.Sprint(tt.expectedTypes) {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong tokens. expected=%v, got=%v", tt.input, tt.expectedTypes, types)))
+		}
+		var starts []string
+		for _, d := range l.Diagnostics() {
+			if d.Code != diagnostic.InvalidUTF8 {
+				t.Errorf(util.RedText(fmt.Sprintf("%q - unexpected diagnostic %s", tt.input, d.Error())))
+			}
+			starts = append(starts, d.Span.Start.String())
+		}
+		if fmt.Sprint(starts) != fmt.Sprint(tt.expectedStarts) {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong diagnostics. expected at %v, got %v", tt.input, tt.expectedStarts, starts)))
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	input := `"foobar" "foo bar" "a\"b\\c\n" "" "x ${f("}")} y" 1`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING, "foobar"},
+		{token.STRING, "foo bar"},
+		{token.STRING, `a\"b\\c\n`},
+		{token.STRING, ""},
+		{token.STRING, `x ${f("}")} y`},
+		{token.INT, "1"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(util.RedText(fmt.Sprintf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)))
+		}
+	}
+	if len(l.Diagnostics()) != 0 {
+		t.Errorf(util.RedText(fmt.Sprintf("unexpected diagnostics: %v", l.Diagnostics())))
+	}
+}
+
+func TestStringDiagnostics(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCode diagnostic.Code
+		expectedText string
+	}{
+		{`let s = "never closed`, diagnostic.UnterminatedString, `"never closed`},
+		{`"multi` + "\n" + `line`, diagnostic.UnterminatedString, `"multi` + "\n" + `line`},
+		{`"bad \q escape"`, diagnostic.InvalidEscape, `\q`},
+		{`"\u{110000}"`, diagnostic.InvalidEscape, `\u{110000}`},
+		{`"\u{}"`, diagnostic.InvalidEscape, `\u{}`},
+		{`"\u12"`, diagnostic.InvalidEscape, `\u`},
+		{`"${"inner never closed}"`, diagnostic.UnterminatedString, `"${"inner never closed}"`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+		diagnostics := l.Diagnostics()
+		if len(diagnostics) != 1 {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - expected 1 diagnostic, got %v", tt.input, diagnostics)))
+			continue
+		}
+		d := diagnostics[0]
+		if d.Code != tt.expectedCode {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong code. expected=%s, got=%s", tt.input, tt.expectedCode, d.Code)))
+		}
+		if actual := tt.input[d.Span.Start.Offset:d.Span.End.Offset]; actual != tt.expectedText {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - diagnostic covers wrong source. expected=%q, got=%q", tt.input, tt.expectedText, actual)))
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected []StringSegment
+	}{
+		{``, []StringSegment{}},
+		{`plain`, []StringSegment{{Text: "plain"}}},
+		{`tab\there \u{48}\u{1F600} \${ \"q\"`, []StringSegment{{Text: "tab\there H😀 ${ \"q\""}}},
+		{`hi ${name}!`, []StringSegment{{Text: "hi "}, {Text: "name", Interpolated: true, Offset: 5}, {Text: "!", Offset: 10}}},
+		{`${a}${ b + 1 }`, []StringSegment{{Text: "a", Interpolated: true, Offset: 2}, {Text: " b + 1 ", Interpolated: true, Offset: 6}}},
+		{`${ f("}") }`, []StringSegment{{Text: ` f("}") `, Interpolated: true, Offset: 2}}},
+	}
+
+	for _, tt := range tests {
+		segments, errors := Segments(tt.raw)
+		if len(errors) != 0 {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - unexpected errors: %v", tt.raw, errors)))
+		}
+		if fmt.Sprint(segments) != fmt.Sprint(tt.expected) {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong segments. expected=%+v, got=%+v", tt.raw, tt.expected, segments)))
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `0 42 1_000_000 0xFF 0Xdead_beef 0o755 0b1010 3.14 1e9 2.5E-3 6.02e+23 1_0.5_0 007 5.foo`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0"},
+		{token.INT, "42"},
+		{token.INT, "1_000_000"},
+		{token.INT, "0xFF"},
+		{token.INT, "0Xdead_beef"},
+		{token.INT, "0o755"},
+		{token.INT, "0b1010"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e9"},
+		{token.FLOAT, "2.5E-3"},
+		{token.FLOAT, "6.02e+23"},
+		{token.FLOAT, "1_0.5_0"},
+		{token.INT, "007"},
+		// A '.' not followed by a digit isn't part of the number
+		{token.INT, "5"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "foo"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(util.RedText(fmt.Sprintf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)))
+		}
+	}
+}
+
+func TestMalformedNumbers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{"0x;", "0x"},
+		{"0b102;", "0b102"},
+		{"0o8;", "0o8"},
+		{"12abc;", "12abc"},
+		{"1e;", "1e"},
+		{"1e+;", "1e+"},
+		{"1__000;", "1__000"},
+		{"100_;", "100_"},
+		{"0x_1;", "0x_1"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.ILLEGAL || tok.Literal != tt.expectedLiteral {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - expected ILLEGAL %q, got=%s %q", tt.input, tt.expectedLiteral, tok.Type, tok.Literal)))
+		}
+		if next := l.NextToken(); next.Type != token.SEMICOLON {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - malformed number wasn't read as one token, next was %s %q", tt.input, next.Type, next.Literal)))
+		}
+		diagnostics := l.Diagnostics()
+		if len(diagnostics) != 1 || diagnostics[0].Code != diagnostic.MalformedNumber {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - expected one malformed number diagnostic, got %v", tt.input, diagnostics)))
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 5; // trailing comment
+/* block /* nested */ still comment */ x / 2 /**/;
+// comment at EOF`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf(util.RedText(fmt.Sprintf("tests[%d] - wrong token. expected=%s %q, got=%s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)))
+		}
+		if tok.Leading != nil || tok.Trailing != nil {
+			t.Errorf(util.RedText(fmt.Sprintf("tests[%d] - trivia kept by a plain lexer", i)))
+		}
+	}
+	if len(l.Diagnostics()) != 0 {
+		t.Errorf(util.RedText(fmt.Sprintf("unexpected diagnostics: %v", l.Diagnostics())))
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	input := "let x = 1; /* outer /* inner */ never closed"
+	l := New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	diagnostics := l.Diagnostics()
+	if len(diagnostics) != 1 || diagnostics[0].Code != diagnostic.UnterminatedComment {
+		t.Fatalf(util.RedText(fmt.Sprintf("expected an unterminated comment diagnostic, got %v", diagnostics)))
+	}
+	if diagnostics[0].Span.Start.Offset != 11 {
+		t.Errorf(util.RedText(fmt.Sprintf("diagnostic should start at the opening /*, got %+v", diagnostics[0].Span.Start)))
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	input := "// header\nlet x = 1; // one\n\n  /* doc */ x\t;\n"
+
+	type trivia struct {
+		kind token.TriviaKind
+		text string
+	}
+	tests := []struct {
+		expectedLiteral  string
+		expectedLeading  []trivia
+		expectedTrailing []trivia
+	}{
+		{"let", []trivia{{token.LINE_COMMENT, "// header"}, {token.WHITESPACE, "\n"}}, []trivia{{token.WHITESPACE, " "}}},
+		{"x", nil, []trivia{{token.WHITESPACE, " "}}},
+		{"=", nil, []trivia{{token.WHITESPACE, " "}}},
+		{"1", nil, nil},
+		{";", nil, []trivia{{token.WHITESPACE, " "}, {token.LINE_COMMENT, "// one"}}},
+		{"x", []trivia{{token.WHITESPACE, "\n\n  "}, {token.BLOCK_COMMENT, "/* doc */"}, {token.WHITESPACE, " "}}, []trivia{{token.WHITESPACE, "\t"}}},
+		{";", nil, nil},
+		{"", []trivia{{token.WHITESPACE, "\n"}}, nil},
+	}
+
+	l := NewWithTrivia(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf(util.RedText(fmt.Sprintf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)))
+		}
+		for _, check := range []struct {
+			name     string
+			expected []trivia
+			actual   []token.Trivia
+		}{{"leading", tt.expectedLeading, tok.Leading}, {"trailing", tt.expectedTrailing, tok.Trailing}} {
+			if len(check.actual) != len(check.expected) {
+				t.Errorf(util.RedText(fmt.Sprintf("tests[%d] - %s trivia wrong. expected=%v, got=%v", i, check.name, check.expected, check.actual)))
+				continue
+			}
+			for j, expected := range check.expected {
+				actual := check.actual[j]
+				if actual.Kind != expected.kind || actual.Text != expected.text {
+					t.Errorf(util.RedText(fmt.Sprintf("tests[%d] - %s trivia %d wrong. expected=%s %q, got=%s %q",
+						i, check.name, j, expected.kind, expected.text, actual.Kind, actual.Text)))
+				}
+				if input[actual.Start.Offset:actual.End.Offset] != actual.Text {
+					t.Errorf(util.RedText(fmt.Sprintf("tests[%d] - %s trivia %d has the wrong span", i, check.name, j)))
+				}
+			}
+		}
+	}
+}
+
+func TestTriviaRoundTrip(t *testing.T) {
+	inputs := []string{
+		"let add = fn(a, b) { a + b; }; // adds\n/* calls\n it */ add(1, 2);\n",
+		"  \n\t// only a comment",
+		`let s = "str ${1 + 2}"; /* a /* nested */ b */ 0x_ /* unterminated`,
+		"",
+	}
+
+	for _, input := range inputs {
+		var out bytes.Buffer
+		l := NewWithTrivia(input)
+		for {
+			tok := l.NextToken()
+			for _, trivia := range tok.Leading {
+				out.WriteString(trivia.Text)
+			}
+			out.WriteString(input[tok.Start.Offset:tok.End.Offset])
+			for _, trivia := range tok.Trailing {
+				out.WriteString(trivia.Text)
+			}
+			if tok.Type == token.EOF {
+				break
+			}
+		}
+		if out.String() != input {
+			t.Errorf(util.RedText(fmt.Sprintf("round trip failed. expected=%q, got=%q", input, out.String())))
+		}
+	}
+}
+
+func TestShebang(t *testing.T) {
+	input := "#!/usr/bin/env clear\nlet x = 1;"
+	l := New(input)
+	if tok := l.NextToken(); tok.Type != token.LET || tok.Start.Line != 2 {
+		t.Errorf(util.RedText(fmt.Sprintf("shebang line wasn't skipped. got=%s %q at %s", tok.Type, tok.Literal, tok.Start)))
+	}
+
+	trivia := NewWithTrivia(input).NextToken().Leading
+	if len(trivia) == 0 || trivia[0].Kind != token.SHEBANG || trivia[0].Text != "#!/usr/bin/env clear" {
+		t.Errorf(util.RedText(fmt.Sprintf("shebang not kept as trivia. got=%v", trivia)))
+	}
+
+	// Anywhere else, '#' is just an illegal character
+	l = New("let x = 1;\n#!/usr/bin/env clear")
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	if len(l.Diagnostics()) == 0 {
+		t.Errorf(util.RedText("shebang after the first line should be reported"))
+	}
+}
+
+// Streaming the source must give exactly the same tokens, positions and diagnostics as lexing it from a string
+func TestReader(t *testing.T) {
+	long := strings.Repeat(`let café = "π ${x}\n" + 0x1F; /* é */ `, CHUNK_SIZE/32)
+	inputs := []string{
+		"let x = 10;\n  x == y",
+		"#!/usr/bin/env clear\nlet π = 3.14; // 变量\n",
+		"\"a\xffb\" é\xc3 \"unterminated ${",
+		"/* nested /* block */ comment */ 0b102 1e 0x",
+		"\"" + strings.Repeat("long string ", CHUNK_SIZE/4) + "\"",
+		long,
+	}
+
+	for i, input := range inputs {
+		readers := []io.Reader{
+			strings.NewReader(input),
+			iotest.OneByteReader(strings.NewReader(input)),
+			iotest.HalfReader(strings.NewReader(input)),
+		}
+		for _, r := range readers {
+			expected, expectedDiagnostics := lexAll(New(input))
+			l := NewReader(r)
+			got, gotDiagnostics := lexAll(l)
+			if l.Err() != nil {
+				t.Fatalf(util.RedText(fmt.Sprintf("inputs[%d] - unexpected read error: %v", i, l.Err())))
+			}
+			if len(got) != len(expected) {
+				t.Fatalf(util.RedText(fmt.Sprintf("inputs[%d] - wrong number of tokens. expected=%d, got=%d", i, len(expected), len(got))))
+			}
+			for j := range expected {
+				if !reflect.DeepEqual(got[j], expected[j]) {
+					t.Fatalf(util.RedText(fmt.Sprintf("inputs[%d] - token %d differs. expected=%+v, got=%+v", i, j, expected[j], got[j])))
+				}
+			}
+			if !reflect.DeepEqual(gotDiagnostics, expectedDiagnostics) {
+				t.Errorf(util.RedText(fmt.Sprintf("inputs[%d] - diagnostics differ. expected=%v, got=%v", i, expectedDiagnostics, gotDiagnostics)))
+			}
+		}
+	}
+
+	// A failing reader ends the source where it failed, and the error is kept for the caller
+	failure := errors.New("disk on fire")
+	l := NewReader(io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(failure)))
+	tokens, _ := lexAll(l)
+	if len(tokens) != 3 || tokens[1].Literal != "x" || !errors.Is(l.Err(), failure) {
+		t.Errorf(util.RedText(fmt.Sprintf("read error not surfaced. got tokens=%v, err=%v", tokens, l.Err())))
+	}
+}
+
+// Reads every token up to and including EOF
+func lexAll(l *Lexer) ([]token.Token, []diagnostic.Diagnostic) {
+	var tokens []token.Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens, l.Diagnostics()
+		}
+	}
+}
+
+var benchmarkSource = strings.Repeat(`let add = fn(x, y) { x + y; };
+let result = add(five, 1_000 * 0xFF); // adds
+let greeting = "hello, ${name}!\n";
+while (i < 10) { i += 1; }
+`, 10_000)
+
+func BenchmarkLexer(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkSource)))
+	for range b.N {
+		drain(New(benchmarkSource))
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	b.SetBytes(int64(len(benchmarkSource)))
+	for range b.N {
+		drain(NewReader(strings.NewReader(benchmarkSource)))
+	}
+}
+
+// Reads every token without keeping them, so benchmarks only measure the Lexer
+func drain(l *Lexer) {
+	for l.NextToken().Type != token.EOF {
+	}
+}
+
+func TestStream(t *testing.T) {
+	s := NewStream(New("let x = { a: 1 };"))
+
+	expectLiteral := func(context string, tok token.Token, expected string) {
+		t.Helper()
+		if tok.Literal != expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%s - wrong token. expected=%q, got=%q", context, expected, tok.Literal)))
+		}
+	}
+
+	expectLiteral("Peek(0)", s.Peek(0), "let")
+	expectLiteral("Peek(4)", s.Peek(4), "a")
+	expectLiteral("Next", s.Next(), "let")
+	expectLiteral("Peek(0) after Next", s.Peek(0), "x")
+
+	// Try reading ahead, then back out of it
+	outer := s.Mark()
+	expectLiteral("Next after Mark", s.Next(), "x")
+	inner := s.Mark()
+	s.Next()
+	s.Next()
+	expectLiteral("Next inside inner mark", s.Next(), "a")
+	s.Reset(inner)
+	expectLiteral("Next after inner Reset", s.Next(), "=")
+	s.Reset(outer)
+	expectLiteral("Next after outer Reset", s.Next(), "x")
+
+	// Releasing keeps what was read
+	m := s.Mark()
+	s.Next()
+	s.Release(m)
+	expectLiteral("Next after Release", s.Next(), "{")
+
+	var rest []string
+	for tok := range s.All() {
+		rest = append(rest, tok.Literal)
+		if tok.Type == token.INT {
+			break
+		}
+	}
+	if fmt.Sprint(rest) != "[a : 1]" {
+		t.Errorf(util.RedText(fmt.Sprintf("All yielded the wrong tokens. got=%q", rest)))
+	}
+	for tok := range s.All() {
+		rest = append(rest, tok.Literal)
+	}
+	if fmt.Sprint(rest) != "[a : 1 } ;]" {
+		t.Errorf(util.RedText(fmt.Sprintf("All didn't pick up where it stopped. got=%q", rest)))
+	}
+	if tok := s.Peek(3); tok.Type != token.EOF {
+		t.Errorf(util.RedText(fmt.Sprintf("peeking past the end should give EOF. got=%s", tok.Type)))
+	}
+}
+
+// Ending a mark out of order must panic, instead of silently ending some other mark
+func TestStreamUnbalancedMarks(t *testing.T) {
+	expectPanic := func(context string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf(util.RedText(fmt.Sprintf("%s - expected a panic", context)))
+			}
+		}()
+		f()
+	}
+
+	s := NewStream(New("a b c d e"))
+	m := s.Mark()
+	s.Next()
+	s.Release(m)
+	expectPanic("second Release", func() { s.Release(m) })
+	expectPanic("Reset after Release", func() { s.Reset(m) })
+
+	// Nested marks must end innermost first, and each only once
+	outer := s.Mark()
+	s.Next()
+	inner := s.Mark()
+	s.Release(inner)
+	expectPanic("second Release of the inner mark", func() { s.Release(inner) })
+	again := s.Mark()
+	expectPanic("Release of an ended mark at the same place as an open one", func() { s.Release(inner) })
+	expectPanic("Release of the outer mark before the inner one", func() { s.Release(outer) })
+	s.Release(again)
+	s.Reset(outer)
+	if tok := s.Next(); tok.Literal != "b" {
+		t.Errorf(util.RedText(fmt.Sprintf("outer mark didn't survive the failed calls. expected=%q, got=%q", "b", tok.Literal)))
+	}
+
+	// The failed calls didn't change anything, so read tokens are still dropped
+	s.Next()
+	s.Next()
+	if len(s.buffer) != 0 || s.Peek(0).Literal != "e" {
+		t.Errorf(util.RedText(fmt.Sprintf("stream state changed by unbalanced marks. buffered=%d, next=%q", len(s.buffer), s.Peek(0).Literal)))
+	}
+}
+
+func TestIllegalTokens(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedReason  token.IllegalReason
+		expectedMessage string // of the diagnostic, empty if it's only reported as invalid UTF-8
+	}{
+		{"@", "@", token.UNEXPECTED_CHARACTER, `unexpected character "@"`},
+		{"@$`?", "@$`?", token.UNEXPECTED_CHARACTER, "unexpected characters \"@$`?\""},
+		{"€€ x", "€€", token.UNEXPECTED_CHARACTER, `unexpected characters "€€"`},
+		{"\xff\xfe", "\xff\xfe", token.UNEXPECTED_CHARACTER, ""},
+		{`"never closed`, `"never closed`, token.UNTERMINATED_STRING, "unterminated string"},
+		{"0b102", "0b102", token.MALFORMED_NUMBER, `malformed number "0b102": unexpected '2' in number`},
+		{`"a \q b"`, `"a \q b"`, token.INVALID_ESCAPE, `unknown escape sequence "\\q"`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.ILLEGAL || tok.Literal != tt.expectedLiteral || tok.Reason != tt.expectedReason {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong token. expected=ILLEGAL %q (%s), got=%s %q (%s)",
+				tt.input, tt.expectedLiteral, tt.expectedReason, tok.Type, tok.Literal, tok.Reason)))
+		}
+		var messages []string
+		for _, d := range l.Diagnostics() {
+			if d.Code != diagnostic.InvalidUTF8 {
+				messages = append(messages, d.Message)
+			}
+		}
+		if tt.expectedMessage == "" && len(messages) != 0 || tt.expectedMessage != "" && fmt.Sprint(messages) != fmt.Sprint([]string{tt.expectedMessage}) {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong diagnostics. expected=%q, got=%q", tt.input, tt.expectedMessage, messages)))
+		}
+	}
+
+	// Tokens that aren't ILLEGAL never have a reason
+	l := New(`let s = "fine" + 0x1F;`)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Reason != "" {
+			t.Errorf(util.RedText(fmt.Sprintf("%s %q has a reason: %s", tok.Type, tok.Literal, tok.Reason)))
+		}
+	}
+}
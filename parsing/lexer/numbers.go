@@ -0,0 +1,103 @@
+package lexer
+
+import (
+	"fmt"
+
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
+	"github.com/ajtroup1/interpreters/parsing/token"
+)
+
+/*
+Reads a number literal, which can be written in any of these forms:
+
+	Ex. 42, 1_000_000            decimal INT
+	Ex. 0xFF, 0o755, 0b1010      hexadecimal, octal and binary INT
+	Ex. 3.14, 1e9, 2.5E-3        FLOAT
+
+Underscores may separate digits to make long numbers readable, but only one at a time and only between two digits
+The literal is kept exactly as written, the parser works out its value
+A malformed number (like 0x, 1e or 12abc) is reported and becomes a single ILLEGAL token
+*/
+func (l *Lexer) readNumber() token.Token {
+	start := l.pos()
+	position := l.position
+	tokenType := token.TokenType(token.INT)
+	problem := ""
+
+	if isDigitFn, ok := basePrefixes[l.peekChar()]; l.ch == '0' && ok {
+		l.readChar()
+		l.readChar()
+		if !l.readDigits(isDigitFn) {
+			problem = "expected digits after the base prefix"
+		}
+	} else {
+		l.readDigits(isDigit)
+		if l.ch == '.' && isDigit(l.peekChar()) {
+			tokenType = token.FLOAT
+			l.readChar()
+			l.readDigits(isDigit)
+		}
+		if l.ch == 'e' || l.ch == 'E' {
+			tokenType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			if !l.readDigits(isDigit) {
+				problem = "expected digits in the exponent"
+			}
+		}
+	}
+
+	// Anything alphanumeric stuck to the end belongs to the same (broken) literal, like the 2 in 0b102
+	if isAlphanumeric(l.ch) {
+		if problem == "" && l.ch == '_' {
+			problem = "'_' must separate two digits"
+		} else if problem == "" {
+			problem = fmt.Sprintf("unexpected %q in number", l.ch)
+		}
+		for isAlphanumeric(l.ch) {
+			l.readChar()
+		}
+	}
+
+	literal := l.input[position:l.position]
+	if problem != "" {
+		l.errorFrom(start, diagnostic.MalformedNumber, fmt.Sprintf("malformed number %q: %s", literal, problem))
+		return token.Token{Type: token.ILLEGAL, Literal: literal, Reason: token.MALFORMED_NUMBER}
+	}
+	return token.Token{Type: tokenType, Literal: literal}
+}
+
+// Maps the char after a leading '0' to the digits allowed in that base
+var basePrefixes = map[rune]func(rune) bool{
+	'x': isHexDigit, 'X': isHexDigit,
+	'o': isOctalDigit, 'O': isOctalDigit,
+	'b': isBinaryDigit, 'B': isBinaryDigit,
+}
+
+/*
+Reads a run of digits, allowing single underscores between them
+Returns false if there wasn't a single digit to read
+*/
+func (l *Lexer) readDigits(isDigitFn func(rune) bool) bool {
+	if !isDigitFn(l.ch) {
+		return false
+	}
+	for isDigitFn(l.ch) || (l.ch == '_' && isDigitFn(l.peekChar())) {
+		l.readChar()
+	}
+	return true
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isOctalDigit(ch rune) bool {
+	return '0' <= ch && ch <= '7'
+}
+
+func isBinaryDigit(ch rune) bool {
+	return ch == '0' || ch == '1'
+}
@@ -0,0 +1,84 @@
+package lexer
+
+import (
+	"errors"
+	"io"
+
+	"github.com/ajtroup1/interpreters/parsing/token"
+)
+
+// How many bytes are read from the source at a time, a token longer than this makes the reads grow to fit it
+const CHUNK_SIZE = 64 * 1024
+
+/*
+Instantiates a Lexer that reads its source from r as it goes, instead of needing it all in memory up front
+Only the token currently being read (plus at most one chunk) is kept buffered, so huge generated files can be lexed
+The tokens, positions and diagnostics are exactly the ones New would give for the same source
+	Ex. lexer.NewReader(file) for a file hundreds of megabytes long
+*/
+func NewReader(r io.Reader) *Lexer {
+	l := &Lexer{reader: r, line: 1, base: token.Position{Line: 1, Column: 1, Offset: 0}}
+	l.readChar()
+	return l
+}
+
+/*
+Returns the error that stopped the Lexer reading its source early, if any
+Reaching the end of the source isn't an error, and a Lexer created with New never has one
+The source is treated as ending wherever the error happened, so check this once the EOF token is reached
+*/
+func (l *Lexer) Err() error {
+	if errors.Is(l.readErr, io.EOF) {
+		return nil
+	}
+	return l.readErr
+}
+
+// Reads from the source until at least n bytes are buffered, or there is nothing left to read
+func (l *Lexer) fill(n int) {
+	for len(l.input) < n && l.load() {
+	}
+}
+
+/*
+Reads the next chunk of the source onto the end of the buffered input
+Returns false once the source is exhausted (or was never streamed)
+Each read is at least as big as what is already buffered, so a token that spans many chunks is still read in linear time
+*/
+func (l *Lexer) load() bool {
+	if l.reader == nil || l.readErr != nil {
+		return false
+	}
+	size := max(CHUNK_SIZE, len(l.input))
+	if cap(l.buf) < size {
+		l.buf = make([]byte, size)
+	}
+	// A whole chunk is read even from readers that hand data over a little at a time, so the buffer isn't recopied for every few bytes
+	n, err := io.ReadFull(l.reader, l.buf[:size])
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	if err != nil {
+		l.readErr = err
+	}
+	if n == 0 {
+		return false
+	}
+	l.input += string(l.buf[:n])
+	return true
+}
+
+/*
+Drops the input in front of the current char, since a streamed Lexer never looks back at a token once it's read
+Offsets are kept relative to the whole source by counting what was dropped
+*/
+func (l *Lexer) discard() {
+	if l.reader == nil {
+		return
+	}
+	n := min(l.position, len(l.input))
+	l.input = l.input[n:]
+	l.position -= n
+	l.readPosition -= n
+	l.discarded += n
+}
@@ -0,0 +1,102 @@
+package lexer
+
+import (
+	"iter"
+
+	"github.com/ajtroup1/interpreters/parsing/token"
+)
+
+/*
+A Stream reads tokens from a Lexer with as much lookahead as the reader needs
+Peek looks any number of tokens ahead, and Mark and Reset let the reader try one reading of the tokens and back out of it
+	Ex. telling a hash literal from a block, or an arrow function from a grouped expression, before committing to either
+Tokens are only lexed when first asked for, and are only kept while something could still look back at them
+*/
+type Stream struct {
+	lexer  *Lexer
+	buffer []token.Token // tokens lexed but not yet dropped, buffer[0] is token number offset
+	offset int           // index in the whole stream of buffer[0]
+	next   int           // index in the whole stream of the token Next returns
+	marks  []Mark        // marks that haven't been reset or released yet, innermost last
+	made   int           // how many marks have ever been made, so every mark is told apart
+}
+
+// A place in a Stream to come back to, see Stream.Mark
+type Mark struct {
+	id       int
+	position int // index in the whole stream of the token Next returned right after the mark
+}
+
+// Instantiates a Stream reading the tokens of l
+func NewStream(l *Lexer) *Stream {
+	return &Stream{lexer: l}
+}
+
+// Returns the next token and moves past it, every call after the end of the input returns EOF
+func (s *Stream) Next() token.Token {
+	tok := s.Peek(0)
+	s.next++
+	// Nothing can reset to a token before the next one while no marks are held, so they can be dropped
+	if len(s.marks) == 0 {
+		s.buffer = s.buffer[s.next-s.offset:]
+		s.offset = s.next
+	}
+	return tok
+}
+
+/*
+Returns the token n places ahead without moving past it
+Peek(0) is the token Next would return, Peek(1) the one after that and so on
+*/
+func (s *Stream) Peek(n int) token.Token {
+	for len(s.buffer) <= s.next-s.offset+n {
+		s.buffer = append(s.buffer, s.lexer.NextToken())
+	}
+	return s.buffer[s.next-s.offset+n]
+}
+
+/*
+Remembers the current place in the Stream, so the tokens read after it can be read again by passing it to Reset
+Every Mark must end with exactly one Reset (to back out) or Release (to keep what was read)
+	Marks can be nested, as long as the inner one ends first
+*/
+func (s *Stream) Mark() Mark {
+	s.made++
+	m := Mark{id: s.made, position: s.next}
+	s.marks = append(s.marks, m)
+	return m
+}
+
+// Goes back to the place m was made at and ends m, the next token is the same one Next returned right after Mark
+func (s *Stream) Reset(m Mark) {
+	s.Release(m)
+	s.next = m.position
+}
+
+/*
+Ends m without going back, once no marks are held the tokens that were read are dropped
+Ending a mark that isn't the innermost open one (because it already ended, or a mark made after it hasn't) is a bug in the caller
+	It panics, rather than silently ending some other mark and leaving the Stream in a state nobody asked for
+*/
+func (s *Stream) Release(m Mark) {
+	if len(s.marks) == 0 || s.marks[len(s.marks)-1] != m {
+		panic("lexer: Stream.Release or Stream.Reset called with a mark that isn't the innermost open one")
+	}
+	s.marks = s.marks[:len(s.marks)-1]
+}
+
+/*
+Returns an iterator over the rest of the tokens, each one is moved past as it's yielded
+The EOF token ends the iteration and isn't yielded itself
+	Ex. for tok := range stream.All() { ... }
+*/
+func (s *Stream) All() iter.Seq[token.Token] {
+	return func(yield func(token.Token) bool) {
+		for {
+			tok := s.Next()
+			if tok.Type == token.EOF || !yield(tok) {
+				return
+			}
+		}
+	}
+}
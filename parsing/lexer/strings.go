@@ -0,0 +1,162 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+String literals are lexed in two steps
+First the Lexer finds where the string ends, which means skipping over escapes and any "${...}" interpolations (which may contain strings themselves)
+The raw contents between the quotes become the STRING token's literal, and Segments later splits them into text and interpolated source
+	Ex. "hi ${name}!\n" = [ { Text: "hi " }, { Text: "name", Interpolated: true }, { Text: "!" + newline } ]
+*/
+type StringSegment struct {
+	Text         string // unescaped text, or the source code of the interpolated expression
+	Interpolated bool
+	Offset       int // byte offset of the segment's source within the raw contents
+}
+
+// A malformed escape sequence found by Segments, located by its byte offset and length within the raw contents
+type StringError struct {
+	Offset  int
+	Length  int
+	Message string
+}
+
+/*
+Returns the index of the closing quote of a string whose contents start at s[i]
+If the string is never closed, the length of s is returned along with false
+*/
+func scanString(s string, i int) (int, bool) {
+	for i < len(s) {
+		switch {
+		case s[i] == '"':
+			return i, true
+		case s[i] == '\\':
+			i += 2
+		case strings.HasPrefix(s[i:], "${"):
+			i = scanInterpolation(s, i+2)
+		default:
+			i++
+		}
+	}
+	return len(s), false
+}
+
+/*
+Returns the index just past the '}' that closes an interpolation whose source starts at s[i]
+Braces are counted so blocks and function literals can be interpolated, and strings inside are skipped whole
+*/
+func scanInterpolation(s string, i int) int {
+	depth := 1
+	for i < len(s) {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '"':
+			end, ok := scanString(s, i+1)
+			if !ok {
+				return len(s)
+			}
+			i = end
+		}
+		i++
+	}
+	return len(s)
+}
+
+/*
+Splits the raw contents of a string literal into plain text and interpolated expressions, resolving escape sequences along the way
+Malformed escapes are kept in the text as they were written and reported as errors
+*/
+func Segments(raw string) ([]StringSegment, []StringError) {
+	segments := []StringSegment{}
+	errors := []StringError{}
+	var text strings.Builder
+	textStart := 0
+
+	flush := func() {
+		if text.Len() > 0 {
+			segments = append(segments, StringSegment{Text: text.String(), Offset: textStart})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(raw); {
+		switch {
+		case raw[i] == '\\':
+			if text.Len() == 0 {
+				textStart = i
+			}
+			decoded, width, err := unescape(raw[i:])
+			if err != "" {
+				errors = append(errors, StringError{Offset: i, Length: width, Message: err})
+				decoded = raw[i : i+width]
+			}
+			text.WriteString(decoded)
+			i += width
+		case strings.HasPrefix(raw[i:], "${"):
+			flush()
+			end := scanInterpolation(raw, i+2)
+			source := strings.TrimSuffix(raw[i+2:end], "}")
+			segments = append(segments, StringSegment{Text: source, Interpolated: true, Offset: i + 2})
+			i = end
+		default:
+			if text.Len() == 0 {
+				textStart = i
+			}
+			text.WriteByte(raw[i])
+			i++
+		}
+	}
+	flush()
+	return segments, errors
+}
+
+/*
+Decodes the escape sequence at the start of s, which always begins with a backslash
+Returns the decoded text, how many bytes of s the escape used, and an error message if it was malformed
+Supported escapes: \n \t \r \0 \" \\ \$ and \u{...} with 1 to 6 hex digits
+*/
+func unescape(s string) (string, int, string) {
+	if len(s) < 2 {
+		return "", len(s), "unfinished escape sequence at end of string"
+	}
+	switch s[1] {
+	case 'n':
+		return "\n", 2, ""
+	case 't':
+		return "\t", 2, ""
+	case 'r':
+		return "\r", 2, ""
+	case '0':
+		return "\x00", 2, ""
+	case '"', '\\', '$':
+		return string(s[1]), 2, ""
+	case 'u':
+		end := strings.IndexByte(s, '}')
+		if len(s) < 3 || s[2] != '{' || end == -1 {
+			return "", 2, `unicode escape must look like \u{XXXX}`
+		}
+		digits := s[3:end]
+		value, err := strconv.ParseUint(digits, 16, 32)
+		if len(digits) == 0 || len(digits) > 6 || err != nil {
+			return "", end + 1, fmt.Sprintf("invalid unicode escape %q", s[:end+1])
+		}
+		if !utf8.ValidRune(rune(value)) {
+			return "", end + 1, fmt.Sprintf("%q is not a valid unicode code point", s[:end+1])
+		}
+		return string(rune(value)), end + 1, ""
+	default:
+		_, size := utf8.DecodeRuneInString(s[1:])
+		return "", 1 + size, fmt.Sprintf("unknown escape sequence %q", s[:1+size])
+	}
+}
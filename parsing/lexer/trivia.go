@@ -0,0 +1,77 @@
+package lexer
+
+import (
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
+	"github.com/ajtroup1/interpreters/parsing/token"
+)
+
+/*
+Reads past any whitespace and comments in front of the Lexer
+If sameLine is true, reading stops at the next newline, which is how a token's trailing trivia is split from the next token's leading trivia
+The trivia is only collected when the Lexer keeps it, otherwise it is just skipped
+*/
+func (l *Lexer) readTrivia(sameLine bool) []token.Trivia {
+	var trivia []token.Trivia
+	for {
+		start := l.pos()
+		position := l.position
+		var kind token.TriviaKind
+
+		switch {
+		case l.ch == '#' && l.peekChar() == '!' && l.discarded+l.position == 0 && l.base.Offset == 0:
+			// A shebang line lets scripts be run directly, and is only allowed as the very first thing in the source
+			kind = token.SHEBANG
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		case isWhitespace(l.ch) && !(sameLine && l.ch == '\n'):
+			kind = token.WHITESPACE
+			for isWhitespace(l.ch) && !(sameLine && l.ch == '\n') {
+				l.readChar()
+			}
+		case l.ch == '/' && l.peekChar() == '/':
+			kind = token.LINE_COMMENT
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		case l.ch == '/' && l.peekChar() == '*':
+			kind = token.BLOCK_COMMENT
+			l.skipBlockComment()
+		default:
+			return trivia
+		}
+
+		if l.keepTrivia {
+			trivia = append(trivia, token.Trivia{
+				Kind:  kind,
+				Text:  l.input[position:l.position],
+				Start: start,
+				End:   l.pos(),
+			})
+		}
+	}
+}
+
+/*
+Skips a block comment, starting on its opening '/*'
+Block comments nest, so commenting out code that already contains a block comment works as expected
+*/
+func (l *Lexer) skipBlockComment() {
+	start := l.pos()
+	depth := 0
+	for l.ch != 0 {
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		}
+		l.readChar()
+		if depth == 0 {
+			return
+		}
+	}
+	l.errorFrom(start, diagnostic.UnterminatedComment, "unterminated block comment")
+}
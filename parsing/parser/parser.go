@@ -8,12 +8,79 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/ajtroup1/interpreters/parsing/ast"
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
 	"github.com/ajtroup1/interpreters/parsing/lexer"
 	"github.com/ajtroup1/interpreters/parsing/token"
 )
 
+/*
+Precedences of every operator in Clear, from lowest to highest
+The higher the precedence, the tighter an operator binds to its operands
+	Ex. in 5 + 5 * 2, '*' has a higher precedence than '+', so it is parsed as 5 + (5 * 2)
+*/
+const (
+	_ int = iota
+	LOWEST
+	ASSIGN      // =
+	OR          // ||
+	AND         // &&
+	EQUALS      // ==
+	LESSGREATER // > or <
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
+	SHIFT       // << or >>
+	SUM         // +
+	PRODUCT     // * or %
+	PREFIX      // -X or !X
+	POWER       // **, binding tighter than a prefix so -2 ** 2 is -(2 ** 2)
+	CALL        // myFunction(X)
+	INDEX       // myArray[X]
+)
+
+// Maps infix operator tokens to their respective precedence
+var precedences = map[token.TokenType]int{
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.OR:              OR,
+	token.AND:             AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.PIPE:            BIT_OR,
+	token.CARET:           BIT_XOR,
+	token.AMPERSAND:       BIT_AND,
+	token.SHIFT_LEFT:      SHIFT,
+	token.SHIFT_RIGHT:     SHIFT,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.POWER:           POWER,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
+}
+
+/*
+Pratt parsing associates parsing functions with token types rather than grammar rules
+A prefix function is called when the token is found at the start of an expression
+An infix function is called when the token is found between two expressions, and receives the left side
+*/
 type (
 	prefixParseFn func() ast.Expression
 	infixParseFn  func(ast.Expression) ast.Expression
@@ -21,24 +88,56 @@ type (
 
 /*
 Overall state for the Parser
-Simply keeps track of the current token and the token X positions away
+Simply keeps track of the current token, and reads the tokens after it from the token Stream as far ahead as a decision needs
 */
 type Parser struct {
 	l              *lexer.Lexer
+	tokens         *lexer.Stream
 	curToken       token.Token
-	peekToken      token.Token
-	errors         []string
+	diagnostics    []diagnostic.Diagnostic
+	panicking      bool // set after an error until the parser synchronizes, so one mistake isn't reported many times
+	blockDepth     int  // number of blocks currently being parsed
+	scopes         []scope
+	loops          []string // labels of the loops being parsed, innermost last, with "" for an unlabelled loop
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		l:      l,
-		errors: []string{},
+		l:           l,
+		tokens:      lexer.NewStream(l),
+		diagnostics: []diagnostic.Diagnostic{},
+		scopes:      []scope{{}},
 	}
-	// Read two tokens, so curToken and peekToken are both set
-	p.nextToken()
+
+	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.registerPrefix(token.IDENT, p.parseIdentifier)
+	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TRUE, p.parseBoolean)
+	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.BANG, p.parsePrefixExpression)
+	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
+	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+
+	p.infixParseFns = make(map[token.TokenType]infixParseFn)
+	for tokenType := range precedences {
+		p.registerInfix(tokenType, p.parseInfixExpression)
+	}
+	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	for _, tokenType := range []token.TokenType{token.ASSIGN, token.PLUS_ASSIGN, token.MINUS_ASSIGN, token.ASTERISK_ASSIGN, token.SLASH_ASSIGN} {
+		p.registerInfix(tokenType, p.parseAssignExpression)
+	}
+
+	// Read the first token, so curToken is set
 	p.nextToken()
 	return p
 }
@@ -50,38 +149,190 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
+/*
+Returns every problem found while parsing, including the ones the lexer ran into, in the order they appear in the source
+*/
+func (p *Parser) Diagnostics() []diagnostic.Diagnostic {
+	all := append(append([]diagnostic.Diagnostic{}, p.l.Diagnostics()...), p.diagnostics...)
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Span.Start.Offset < all[j].Span.Start.Offset
+	})
+	return all
+}
+
+// Flattens the diagnostics into single-line messages, which is all most tests need
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := []string{}
+	for _, d := range p.Diagnostics() {
+		errors = append(errors, d.Error())
+	}
+	return errors
+}
+
+/*
+Records a new error diagnostic covering the given span and puts the parser in panic mode
+While panicking, any further errors are just knock-on effects of the first one, so they are thrown away
+*/
+func (p *Parser) errorAt(span token.Span, code diagnostic.Code, msg string) *diagnostic.Diagnostic {
+	if p.panicking {
+		return &diagnostic.Diagnostic{}
+	}
+	p.panicking = true
+	p.diagnostics = append(p.diagnostics, diagnostic.Diagnostic{
+		Span:     span,
+		Severity: diagnostic.Error,
+		Code:     code,
+		Message:  msg,
+	})
+	return &p.diagnostics[len(p.diagnostics)-1]
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	if p.illegalToken(p.peekTokenN(1)) {
+		return
+	}
+	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekTokenN(1).Type)
+	d := p.errorAt(tokenSpan(p.peekTokenN(1)), diagnostic.UnexpectedToken, msg)
+	// Punctuation token types are spelled exactly like their literal, so they're the only ones we can suggest typing in
+	if !strings.ContainsFunc(string(t), unicode.IsLetter) {
+		d.Fix = fmt.Sprintf("insert %q after %q", string(t), p.curToken.Literal)
+	}
+}
+
+func (p *Parser) noPrefixParseFnError(t token.TokenType) {
+	if p.illegalToken(p.curToken) {
+		return
+	}
+	msg := fmt.Sprintf("no prefix parse function for %s found", t)
+	d := p.errorAt(tokenSpan(p.curToken), diagnostic.ExpectedExpression, msg)
+	d.Notes = append(d.Notes, fmt.Sprintf("expected an expression, but %q cannot start one", p.curToken.Literal))
+}
+
+/*
+Enters panic mode if tok is ILLEGAL, without reporting anything of its own
+The lexer has already reported why the token is illegal (an unexpected character, an unterminated string, a malformed number or an invalid escape)
+	"expected ), got ILLEGAL" on top of that would only be noise
+*/
+func (p *Parser) illegalToken(tok token.Token) bool {
+	if tok.Type != token.ILLEGAL {
+		return false
+	}
+	p.panicking = true
+	return true
+}
+
+/*
+The names bound in one scope of the program, so assigning to a constant can be caught before the program runs
+Functions and for loops open a new scope, the same way they get their own environment when evaluated
+*/
+type scope map[string]binding
+
+type binding struct {
+	constant bool
+	name     *ast.Identifier // where the name was bound
+}
+
+func (p *Parser) pushScope() { p.scopes = append(p.scopes, scope{}) }
+func (p *Parser) popScope()  { p.scopes = p.scopes[:len(p.scopes)-1] }
+
+func (p *Parser) declare(name *ast.Identifier, constant bool) {
+	p.scopes[len(p.scopes)-1][name.Value] = binding{constant: constant, name: name}
+}
+
+// Finds the binding a name refers to, looking outwards from the innermost scope
+func (p *Parser) resolve(name string) (binding, bool) {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if b, ok := p.scopes[i][name]; ok {
+			return b, true
+		}
+	}
+	return binding{}, false
 }
 
 // Small helper function to advance both the current and peek token
 func (p *Parser) nextToken() {
-	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.curToken = p.tokens.Next()
 }
 
 /*
 Encounters each new statement one by one and parses it then adds it to the Program's statement list
+Statements containing errors are left out, and parsing picks back up at the next statement so every independent error gets reported
 */
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
-	for p.curToken.Type != token.EOF {
-		stmt := p.parseStatement()
-		if stmt != nil {
+	for !p.curTokenIs(token.EOF) {
+		if stmt := p.parseStatementRecovering(); stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
-		p.nextToken()
 	}
 	return program
 }
 
+/*
+Parses the statement at curToken and moves on to the first token of the next one
+If anything inside the statement was malformed (even if that was already recovered from, like in a function body), nil is returned
+*/
+func (p *Parser) parseStatementRecovering() ast.Statement {
+	start := p.curToken
+	errorsBefore := len(p.diagnostics)
+	stmt := p.parseStatement()
+	if p.panicking {
+		p.synchronize(start)
+		return nil
+	}
+	p.nextToken()
+	if len(p.diagnostics) > errorsBefore {
+		return nil
+	}
+	return stmt
+}
+
+// Tokens that can only begin a new statement, making them safe places to resume parsing after an error
+var statementStarts = map[token.TokenType]bool{
+	token.LET:      true,
+	token.CONST:    true,
+	token.RETURN:   true,
+	token.WHILE:    true,
+	token.FOR:      true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+}
+
+/*
+Panic-mode error recovery
+Skips tokens until the parser reaches a statement boundary, leaving curToken on the first token of the next statement:
+	- just past a ';'
+	- on a '}' closing the block being parsed, so the block can close itself
+	- on a keyword that starts a statement
+	- on EOF, which is never skipped
+Braces opened while skipping are skipped along with everything in them, so a broken function body is thrown away as a whole
+The token the broken statement started on is always skipped, so recovery can't get stuck in place
+*/
+func (p *Parser) synchronize(start token.Token) {
+	p.panicking = false
+	if p.curToken.Start == start.Start && !p.curTokenIs(token.EOF) {
+		p.nextToken()
+	}
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		switch {
+		case p.curTokenIs(token.LBRACE):
+			depth++
+		case p.curTokenIs(token.RBRACE) && depth > 0:
+			depth--
+		case p.curTokenIs(token.RBRACE) && p.blockDepth > 0:
+			return
+		case p.curTokenIs(token.SEMICOLON) && depth == 0:
+			p.nextToken()
+			return
+		case statementStarts[p.curToken.Type] && depth == 0:
+			return
+		}
+		p.nextToken()
+	}
+}
+
 /*
 **STATEMENT PARSING**
  */
@@ -92,53 +343,772 @@ Similarly to the lexer, it switches the type of statement and calls the respecti
 Returns the structured node and appends to Program in ParseProgram
 */
 func (p *Parser) parseStatement() ast.Statement {
+	// The nil checks keep a failed parse from turning into a non-nil ast.Statement holding a nil pointer
 	switch p.curToken.Type {
-	case token.LET:
-		return p.parseLetStatement()
+	case token.LET, token.CONST:
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
 	case token.RETURN:
-		return p.parseReturnStatement()
+		if stmt := p.parseReturnStatement(); stmt != nil {
+			return stmt
+		}
+	case token.WHILE:
+		return p.parseWhileStatement(nil)
+	case token.FOR:
+		return p.parseForStatement(nil)
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControl()
+	case token.IDENT:
+		if p.peekTokenIs(token.COLON) {
+			return p.parseLabelledLoop()
+		}
+		if stmt := p.parseExpressionStatement(); stmt != nil {
+			return stmt
+		}
 	default:
-		return nil
+		if stmt := p.parseExpressionStatement(); stmt != nil {
+			return stmt
+		}
 	}
+	return nil
 }
 
-// Handles assigning let statement information to a corresponding Let node
+/*
+Handles assigning let and const statement information to a corresponding Let node
+The name is only bound once its value is parsed, so the value can still refer to an outer binding of the same name
+*/
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal, Loc: p.spanFrom(p.curToken)}
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
-	// TODO: We're skipping the expressions until we
-	// encounter a semicolon
-	for !p.curTokenIs(token.SEMICOLON) {
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
+	stmt.Loc = p.spanFrom(stmt.Token)
+	p.declare(stmt.Name, stmt.Constant())
 	return stmt
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 	p.nextToken()
-	// TODO: We're skipping the expressions until we
-	// encounter a semicolon
-	for !p.curTokenIs(token.SEMICOLON) {
+	stmt.ReturnValue = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	stmt.Loc = p.spanFrom(stmt.Token)
+	return stmt
+}
+
+/*
+Any statement that isn't a let or return statement is an expression standing on its own
+	Ex. x + 10;
+The trailing semicolon is optional so expressions like "5 + 5" can be typed into the REPL
+*/
+func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	stmt.Loc = p.spanFrom(stmt.Token)
+	return stmt
+}
+
+/*
+A label names the loop that follows it, so break and continue in a nested loop can refer to it
+	Ex. outer: for (x in xs) { for (y in ys) { continue outer; } }
+*/
+func (p *Parser) parseLabelledLoop() ast.Statement {
+	label := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal, Loc: p.spanFrom(p.curToken)}
+	p.nextToken()
+	p.nextToken()
+	switch p.curToken.Type {
+	case token.WHILE:
+		return p.parseWhileStatement(label)
+	case token.FOR:
+		return p.parseForStatement(label)
+	}
+	if p.illegalToken(p.curToken) {
+		return nil
+	}
+	msg := fmt.Sprintf("expected a loop after the label %s, got %s instead", label.Value, p.curToken.Type)
+	p.errorAt(tokenSpan(p.curToken), diagnostic.UnexpectedToken, msg)
+	return nil
+}
+
+func (p *Parser) parseWhileStatement(label *ast.Identifier) ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken, Label: label}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	open := p.curToken
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		p.skipLoop(open)
+		return nil
+	}
+	if stmt.Body = p.parseLoopBody(label); stmt.Body == nil {
+		return nil
+	}
+	stmt.Loc = p.loopSpan(label, stmt.Token)
+	return stmt
+}
+
+/*
+Parses both kinds of for loop, telling them apart by whether the parentheses start with "name in"
+The loop gets its own scope, so a binding made by its init or its variable doesn't outlive it
+*/
+func (p *Parser) parseForStatement(label *ast.Identifier) ast.Statement {
+	forToken := p.curToken
+	// Both kinds of for loop start with "for (", so look past the '(' to tell them apart
+	forIn := p.peekTokenN(2).Type == token.IDENT && p.peekTokenN(3).Type == token.IN
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	open := p.curToken
+	p.nextToken()
+	p.pushScope()
+	defer p.popScope()
+	if forIn {
+		return p.parseForInStatement(forToken, open, label)
+	}
+
+	stmt := &ast.ForStatement{Token: forToken, Label: label}
+	if !p.curTokenIs(token.SEMICOLON) {
+		if p.curTokenIs(token.LET) || p.curTokenIs(token.CONST) {
+			if init := p.parseLetStatement(); init != nil {
+				stmt.Init = init
+			}
+		} else if init := p.parseExpressionStatement(); init != nil {
+			stmt.Init = init
+		}
+		if stmt.Init == nil || !p.curTokenIs(token.SEMICOLON) && !p.expectPeek(token.SEMICOLON) {
+			p.skipLoop(open)
+			return nil
+		}
+	}
+	if !p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.SEMICOLON) {
+		p.skipLoop(open)
+		return nil
+	}
+	if !p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
+		stmt.Step = p.parseExpression(LOWEST)
 	}
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		p.skipLoop(open)
+		return nil
+	}
+	if stmt.Body = p.parseLoopBody(label); stmt.Body == nil {
+		return nil
+	}
+	stmt.Loc = p.loopSpan(label, stmt.Token)
 	return stmt
 }
 
+// Starts on the loop variable, the iterable is parsed before the variable is bound so it can't refer to it
+func (p *Parser) parseForInStatement(forToken, open token.Token, label *ast.Identifier) ast.Statement {
+	stmt := &ast.ForInStatement{Token: forToken, Label: label}
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal, Loc: p.spanFrom(p.curToken)}
+	p.nextToken()
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		p.skipLoop(open)
+		return nil
+	}
+	p.declare(stmt.Variable, false)
+	if stmt.Body = p.parseLoopBody(label); stmt.Body == nil {
+		return nil
+	}
+	stmt.Loc = p.loopSpan(label, stmt.Token)
+	return stmt
+}
+
+/*
+Skips the rest of a loop whose header is broken, leaving curToken just past the body's closing '}'
+The header ends at the ')' matching open, or at a '{' directly inside it, since that is most likely the body after a missing ')'
+Without this, recovery would stop at a ';' in the header and try to parse the rest of the loop as statements
+*/
+func (p *Parser) skipLoop(open token.Token) {
+	depth := 1
+	for !p.curTokenIs(token.EOF) && !(p.curTokenIs(token.LBRACE) && depth == 1) {
+		switch {
+		case p.curToken.Start == open.Start:
+		case p.curTokenIs(token.LPAREN):
+			depth++
+		case p.curTokenIs(token.RPAREN):
+			depth--
+		}
+		if depth == 0 {
+			p.nextToken()
+			break
+		}
+		p.nextToken()
+	}
+	if p.curTokenIs(token.LBRACE) {
+		p.skipBraces(p.curToken)
+	}
+}
+
+// Parses the block of a loop, during which break and continue can refer to the loop
+func (p *Parser) parseLoopBody(label *ast.Identifier) *ast.BlockStatement {
+	name := ""
+	if label != nil {
+		name = label.Value
+	}
+	p.loops = append(p.loops, name)
+	body := p.parseBlockStatement()
+	p.loops = p.loops[:len(p.loops)-1]
+	return body
+}
+
+// A loop's span starts at its label if it has one, and takes in an optional trailing ';'
+func (p *Parser) loopSpan(label *ast.Identifier, loopToken token.Token) token.Span {
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if label != nil {
+		return p.spanFrom(label.Token)
+	}
+	return p.spanFrom(loopToken)
+}
+
+/*
+Parses a break or continue, which are only allowed inside a loop of the same function
+A label has to name one of the loops around the statement
+*/
+func (p *Parser) parseLoopControl() ast.Statement {
+	tok := p.curToken
+	var label *ast.Identifier
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		label = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal, Loc: p.spanFrom(p.curToken)}
+	}
+	if len(p.loops) == 0 {
+		p.errorAt(p.spanFrom(tok), diagnostic.MisplacedLoopControl, fmt.Sprintf("%s is only allowed inside a loop", tok.Literal))
+		return nil
+	}
+	if label != nil && !slices.Contains(p.loops, label.Value) {
+		d := p.errorAt(label.Span(), diagnostic.UnknownLabel, fmt.Sprintf("no loop labelled %s around this %s", label.Value, tok.Literal))
+		d.Notes = append(d.Notes, fmt.Sprintf("a label goes in front of a loop, like %s: while (...) { ... }", label.Value))
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok, Label: label, Loc: p.spanFrom(tok)}
+	}
+	return &ast.ContinueStatement{Token: tok, Label: label, Loc: p.spanFrom(tok)}
+}
+
+/*
+Parses statements until the closing '}' of the block, leaving curToken on the '}'
+Errors inside the block are recovered from the same way as in ParseProgram, so the rest of the block still gets checked
+*/
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.curToken}
+	block.Statements = []ast.Statement{}
+	p.nextToken()
+
+	p.blockDepth++
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		if stmt := p.parseStatementRecovering(); stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+	}
+	p.blockDepth--
+
+	if !p.curTokenIs(token.RBRACE) {
+		msg := fmt.Sprintf("expected %s to close the block, got %s instead", token.RBRACE, p.curToken.Type)
+		d := p.errorAt(tokenSpan(p.curToken), diagnostic.UnexpectedToken, msg)
+		d.Notes = append(d.Notes, fmt.Sprintf("the block was opened at %s", block.Token.Start))
+		return nil
+	}
+	block.Loc = p.spanFrom(block.Token)
+	return block
+}
+
+/*
+**EXPRESSION PARSING**
+ */
+
+/*
+The heart of the Pratt parser
+Parses the prefix part of the expression, then keeps folding it into infix expressions
+Folding stops once the next operator doesn't bind tighter than the precedence this call was made with
+*/
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken.Type)
+		return nil
+	}
+	leftExp := prefix()
+
+	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+		infix := p.infixParseFns[p.peekTokenN(1).Type]
+		if infix == nil {
+			return leftExp
+		}
+		p.nextToken()
+		leftExp = infix(leftExp)
+	}
+	return leftExp
+}
+
+func (p *Parser) parseIdentifier() ast.Expression {
+	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal, Loc: p.spanFrom(p.curToken)}
+}
+
+/*
+Works out the value of an INT token, which may have a 0x, 0o or 0b prefix and underscores between digits
+Values too big for 64 bits are kept as big integers rather than being rejected
+*/
+func (p *Parser) parseIntegerLiteral() ast.Expression {
+	lit := &ast.IntegerLiteral{Token: p.curToken, Loc: p.spanFrom(p.curToken)}
+	digits := strings.ReplaceAll(p.curToken.Literal, "_", "")
+	base := 10
+	if len(digits) > 2 && digits[0] == '0' {
+		if prefixBase, ok := integerBases[digits[1]]; ok {
+			base = prefixBase
+			digits = digits[2:]
+		}
+	}
+
+	value, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
+		p.errorAt(lit.Loc, diagnostic.InvalidInteger, msg)
+		return nil
+	}
+	if value.IsInt64() {
+		lit.Value = value.Int64()
+	} else {
+		lit.Big = value
+	}
+	return lit
+}
+
+// Maps the char after a leading '0' in an integer literal to its base
+var integerBases = map[byte]int{'x': 16, 'X': 16, 'o': 8, 'O': 8, 'b': 2, 'B': 2}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken, Loc: p.spanFrom(p.curToken)}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(p.curToken.Literal, "_", ""), 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		d := p.errorAt(lit.Loc, diagnostic.InvalidFloat, msg)
+		d.Notes = append(d.Notes, "floats must be within the range of a 64-bit float")
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+/*
+Turns a STRING token into a StringLiteral, or an InterpolatedString if it contains any "${...}"
+Escape sequences were already checked by the lexer, so any problems with them aren't reported again here
+*/
+func (p *Parser) parseStringLiteral() ast.Expression {
+	tok := p.curToken
+	loc := p.spanFrom(tok)
+	segments, _ := lexer.Segments(tok.Literal)
+
+	interpolated := false
+	for _, segment := range segments {
+		interpolated = interpolated || segment.Interpolated
+	}
+	if !interpolated {
+		var value strings.Builder
+		for _, segment := range segments {
+			value.WriteString(segment.Text)
+		}
+		return &ast.StringLiteral{Token: tok, Value: value.String(), Loc: loc}
+	}
+
+	str := &ast.InterpolatedString{Token: tok, Loc: loc}
+	contentStart := tok.Start.Advance(`"`)
+	for _, segment := range segments {
+		start := contentStart.Advance(tok.Literal[:segment.Offset])
+		if !segment.Interpolated {
+			span := token.Span{Start: start, End: start.Advance(tok.Literal[segment.Offset:])}
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: tok, Value: segment.Text, Loc: span})
+			continue
+		}
+		exp := p.parseInterpolation(segment.Text, start)
+		if exp == nil {
+			return nil
+		}
+		str.Parts = append(str.Parts, exp)
+	}
+	return str
+}
+
+/*
+Parses the source inside a "${...}" with a parser of its own, positioned so its spans and diagnostics point into the string
+The interpolation has to hold exactly one expression
+*/
+func (p *Parser) parseInterpolation(source string, start token.Position) ast.Expression {
+	inner := New(lexer.NewAt(source, start))
+	program := inner.ParseProgram()
+	if diagnostics := inner.Diagnostics(); len(diagnostics) != 0 {
+		// The lexer already reported any invalid UTF-8 while reading the whole string
+		for _, d := range diagnostics {
+			if d.Code != diagnostic.InvalidUTF8 {
+				p.diagnostics = append(p.diagnostics, d)
+			}
+		}
+		p.panicking = true
+		return nil
+	}
+
+	span := token.Span{Start: start, End: start.Advance(source)}
+	if len(program.Statements) != 1 {
+		p.errorAt(span, diagnostic.InvalidInterpolation, fmt.Sprintf("interpolation must contain exactly one expression, found %d statements", len(program.Statements)))
+		return nil
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		p.errorAt(span, diagnostic.InvalidInterpolation, fmt.Sprintf("interpolation must contain an expression, found a %s statement", program.Statements[0].TokenLiteral()))
+		return nil
+	}
+	return stmt.Expression
+}
+
+func (p *Parser) parseBoolean() ast.Expression {
+	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE), Loc: p.spanFrom(p.curToken)}
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(PREFIX)
+	expression.Loc = p.spanFrom(expression.Token)
+	return expression
+}
+
+/*
+Parses the right side of the operator with the operator's own precedence, making infix operators left-associative
+The exception is '**', whose right side is parsed with a lower precedence so it takes in further '**'s
+	Ex. 2 ** 3 ** 2 is 2 ** (3 ** 2)
+*/
+func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+	precedence := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		precedence--
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+	expression.Loc = p.spanBetween(left, expression.Token)
+	return expression
+}
+
+/*
+Parses if (condition) { ... } with an optional else branch
+	Ex. if (x < 0) { -x } else if (x == 0) { 0 } else { x }
+An 'else if' is parsed recursively and wrapped in a block, so Alternative is always a block
+*/
+func (p *Parser) parseIfExpression() ast.Expression {
+	expression := &ast.IfExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	expression.Consequence = p.parseBlockStatement()
+	if expression.Consequence == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			elseIf, ok := p.parseIfExpression().(*ast.IfExpression)
+			if !ok {
+				return nil
+			}
+			expression.Alternative = &ast.BlockStatement{
+				Token:      elseIf.Token,
+				Statements: []ast.Statement{&ast.ExpressionStatement{Token: elseIf.Token, Expression: elseIf, Loc: elseIf.Loc}},
+				Loc:        elseIf.Loc,
+			}
+		} else {
+			if !p.expectPeek(token.LBRACE) {
+				return nil
+			}
+			expression.Alternative = p.parseBlockStatement()
+			if expression.Alternative == nil {
+				return nil
+			}
+		}
+	}
+	expression.Loc = p.spanFrom(expression.Token)
+	return expression
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	lit.Parameters = p.parseFunctionParameters()
+	if lit.Parameters == nil || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// A function body gets its own scope, and break and continue can't reach loops outside of it
+	p.pushScope()
+	defer p.popScope()
+	for _, param := range lit.Parameters {
+		p.declare(param, false)
+	}
+	outerLoops := p.loops
+	p.loops = nil
+	defer func() { p.loops = outerLoops }()
+
+	lit.Body = p.parseBlockStatement()
+	if lit.Body == nil {
+		return nil
+	}
+	lit.Loc = p.spanFrom(lit.Token)
+	return lit
+}
+
+// Parses a comma-separated list of identifiers up to and including the closing ')'
+func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	identifiers := []*ast.Identifier{}
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return identifiers
+	}
+
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal, Loc: p.spanFrom(p.curToken)})
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return identifiers
+}
+
+// A '(' following an expression is a call, with the expression before it being the function
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return nil
+	}
+	exp.Loc = p.spanBetween(function, exp.Token)
+	return exp
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+	array.Loc = p.spanFrom(array.Token)
+	return array
+}
+
+/*
+Parses a hash literal, a comma-separated list of key: value pairs between braces
+A '{' that starts an expression is always a hash, blocks only appear where the grammar asks for one (after if, else and fn)
+	Ex. {"a": 1, 2: true}
+*/
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []ast.HashPair{}}
+	errorsBefore := len(p.diagnostics)
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+		if !p.expectPeek(token.COLON) {
+			// Most likely a block was written where only an expression can go, so say why a hash was expected
+			if len(hash.Pairs) == 0 && len(p.diagnostics) > errorsBefore {
+				d := &p.diagnostics[errorsBefore]
+				d.Notes = append(d.Notes, fmt.Sprintf("the %s at %s starts a hash literal, blocks can only follow if, else and fn", token.LBRACE, hash.Token.Start))
+			}
+			p.skipBraces(hash.Token)
+			return nil
+		}
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			p.skipBraces(hash.Token)
+			return nil
+		}
+	}
+	p.nextToken()
+	hash.Loc = p.spanFrom(hash.Token)
+	return hash
+}
+
+/*
+Skips the rest of a broken hash literal (or anything else in braces), leaving curToken just past its closing '}'
+Otherwise recovery would stop at that '}', mistaking it for the end of the block the hash is in
+*/
+func (p *Parser) skipBraces(open token.Token) {
+	depth := 1
+	for !p.curTokenIs(token.EOF) {
+		switch {
+		case p.curToken.Start == open.Start:
+		case p.curTokenIs(token.LBRACE):
+			depth++
+		case p.curTokenIs(token.RBRACE):
+			depth--
+		}
+		p.nextToken()
+		if depth == 0 {
+			return
+		}
+	}
+}
+
+/*
+Parses an assignment, which is right associative so a = b = c assigns c to both
+Only places that can hold a value can be assigned to
+*/
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{Token: p.curToken, Target: target}
+	switch target := target.(type) {
+	case *ast.IndexExpression:
+	case *ast.Identifier:
+		if b, ok := p.resolve(target.Value); ok && b.constant {
+			d := p.errorAt(target.Span(), diagnostic.ConstantAssignment, fmt.Sprintf("cannot assign to %s, it is a constant", target.Value))
+			d.Notes = append(d.Notes, fmt.Sprintf("%s was declared with const at %s", target.Value, b.name.Span().Start))
+			d.Fix = fmt.Sprintf("declare %s with let instead of const", target.Value)
+			return nil
+		}
+	default:
+		if target != nil {
+			p.errorAt(target.Span(), diagnostic.InvalidAssignment, fmt.Sprintf("cannot assign to %s", target))
+		}
+		return nil
+	}
+	p.nextToken()
+	exp.Value = p.parseExpression(ASSIGN - 1)
+	exp.Loc = p.spanBetween(target, exp.Token)
+	return exp
+}
+
+// Parses a comma-separated list of expressions up to and including the end token, like the arguments of a call or the elements of an array
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	list = append(list, p.parseExpression(LOWEST))
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		list = append(list, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+	return list
+}
+
+/*
+Parses what follows a '[' after an expression, which is either an index or a slice
+A slice has a ':' between its bounds, either of which can be left out
+	Ex. a[1], a[1:3], a[:3], a[1:], a[:]
+*/
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	start := p.curToken
+	var low ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		low = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.COLON) {
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: start, Left: left, Index: low, Loc: p.spanBetween(left, start)}
+		}
+	}
+
+	p.nextToken()
+	slice := &ast.SliceExpression{Token: start, Left: left, Low: low}
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		slice.High = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	slice.Loc = p.spanBetween(left, start)
+	return slice
+}
+
+// Parentheses don't get their own node, they just reset the precedence to LOWEST for the expression within them
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	p.nextToken()
+	exp := p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return exp
+}
+
 // -----------------------------------------------------------------------------------------
 
 // Conditional functions that act as type checks for current and peek token
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
+/*
+Returns the token n places after curToken without moving past it, so peekTokenN(1) is the very next token
+The Stream keeps lexing ahead as needed, so the parser can look as far as it has to before deciding what it's reading
+*/
+func (p *Parser) peekTokenN(n int) token.Token {
+	return p.tokens.Peek(n - 1)
+}
+
 func (p *Parser) peekTokenIs(t token.TokenType) bool {
-	return p.peekToken.Type == t
+	return p.peekTokenN(1).Type == t
 }
 
 // Conditional returning whether the peeked token is a sent (param) token type
@@ -151,3 +1121,36 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 		return false
 	}
 }
+
+// Returns the span covered by a single token
+func tokenSpan(tok token.Token) token.Span {
+	return token.Span{Start: tok.Start, End: tok.End}
+}
+
+/*
+Spans always end at the current token, since every parsing function leaves the parser on the last token of the node it built
+spanFrom starts the span at the given token, spanBetween starts it at an already-parsed node (falling back to the token if that node is missing)
+*/
+func (p *Parser) spanFrom(start token.Token) token.Span {
+	return token.Span{Start: start.Start, End: p.curToken.End}
+}
+func (p *Parser) spanBetween(start ast.Node, fallback token.Token) token.Span {
+	if start == nil {
+		return p.spanFrom(fallback)
+	}
+	return token.Span{Start: start.Span().Start, End: p.curToken.End}
+}
+
+// Returns the precedence of the peek/current token, or LOWEST if it isn't an operator
+func (p *Parser) peekPrecedence() int {
+	if p, ok := precedences[p.peekTokenN(1).Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+func (p *Parser) curPrecedence() int {
+	if p, ok := precedences[p.curToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
@@ -3,10 +3,13 @@ package parser
 import (
 	"fmt"
 	"log"
+	"strings"
 	"testing"
 
 	"github.com/ajtroup1/interpreters/parsing/ast"
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
 	"github.com/ajtroup1/interpreters/parsing/lexer"
+	"github.com/ajtroup1/interpreters/parsing/token"
 	"github.com/ajtroup1/interpreters/util"
 )
 
@@ -76,6 +79,929 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestLetStatementValues(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedIdentifier string
+		expectedValue      string
+	}{
+		{"let x = 5;", "x", "5"},
+		{"let y = x + 10;", "y", "(x + 10)"},
+		{"let foobar = -y * 2;", "foobar", "((-y) * 2)"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt := program.Statements[0]
+		if !testLetStatement(t, stmt, tt.expectedIdentifier) {
+			return
+		}
+		value := stmt.(*ast.LetStatement).Value
+		if value == nil || value.String() != tt.expectedValue {
+			t.Errorf(util.RedText(fmt.Sprintf("let value wrong. expected=%q, got=%v", tt.expectedValue, value)))
+		}
+	}
+}
+
+func TestReturnStatementValues(t *testing.T) {
+	program := parseInput(t, "return x * (y + 1);")
+	returnStmt, ok := program.Statements[0].(*ast.ReturnStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ReturnStatement. got=%T", program.Statements[0])
+	}
+	if returnStmt.ReturnValue == nil || returnStmt.ReturnValue.String() != "(x * (y + 1))" {
+		t.Errorf("returnStmt.ReturnValue wrong. got=%v", returnStmt.ReturnValue)
+	}
+}
+
+func TestIdentifierExpression(t *testing.T) {
+	program := parseInput(t, "foobar;")
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	testIdentifier(t, stmt.Expression, "foobar")
+}
+
+func TestIntegerLiteralExpression(t *testing.T) {
+	program := parseInput(t, "5;")
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	testIntegerLiteral(t, stmt.Expression, 5)
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1_000_000", 1000000},
+		{"0xFF", 255},
+		{"0o755", 493},
+		{"0b1010", 10},
+		{"010", 10},
+		{"9223372036854775807", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		testIntegerLiteral(t, program.Statements[0].(*ast.ExpressionStatement).Expression, tt.expected)
+	}
+
+	bigInput := "123_456_789_012_345_678_901_234_567_890"
+	program := parseInput(t, bigInput)
+	lit := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IntegerLiteral)
+	if lit.Big == nil || lit.Big.String() != "123456789012345678901234567890" {
+		t.Errorf("big integer literal wrong. got=%v", lit.Big)
+	}
+
+	floatTests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14", 3.14},
+		{"1e9", 1e9},
+		{"2.5E-3", 0.0025},
+		{"1_000.000_1", 1000.0001},
+	}
+
+	for _, tt := range floatTests {
+		program := parseInput(t, tt.input)
+		lit, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+		}
+		if lit.Value != tt.expected {
+			t.Errorf("float value wrong. expected=%g, got=%g", tt.expected, lit.Value)
+		}
+	}
+}
+
+func TestBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedBoolean bool
+	}{
+		{"true;", true},
+		{"false;", false},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		boolean, ok := stmt.Expression.(*ast.Boolean)
+		if !ok {
+			t.Fatalf("exp not *ast.Boolean. got=%T", stmt.Expression)
+		}
+		if boolean.Value != tt.expectedBoolean {
+			t.Errorf("boolean.Value not %t. got=%t", tt.expectedBoolean, boolean.Value)
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello world";`, "hello world"},
+		{`"line\nbreak \"quoted\" \u{263A}"`, "line\nbreak \"quoted\" \u263A"},
+		{`""`, ""},
+		{`"costs \${price}"`, "costs ${price}"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestInterpolatedString(t *testing.T) {
+	input := `let s = "hi ${first + " " + last}, you are ${age * 2}!";`
+	program := parseInput(t, input)
+	value := program.Statements[0].(*ast.LetStatement).Value
+	str, ok := value.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", value)
+	}
+
+	expected := []string{`"hi "`, `((first + " ") + last)`, `", you are "`, `(age * 2)`, `"!"`}
+	if len(str.Parts) != len(expected) {
+		t.Fatalf("wrong number of parts. expected=%d, got=%d", len(expected), len(str.Parts))
+	}
+	for i, part := range str.Parts {
+		if part.String() != expected[i] {
+			t.Errorf("part %d wrong. expected=%q, got=%q", i, expected[i], part.String())
+		}
+	}
+
+	// Spans inside the interpolation point into the original source
+	span := str.Parts[3].Span()
+	if actual := input[span.Start.Offset:span.End.Offset]; actual != "age * 2" {
+		t.Errorf("interpolated expression span wrong. got=%q", actual)
+	}
+	if str.String() != `"hi ${((first + " ") + last)}, you are ${(age * 2)}!"` {
+		t.Errorf("str.String() wrong. got=%q", str.String())
+	}
+}
+
+func TestInterpolationDiagnostics(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedCode  diagnostic.Code
+		expectedStart token.Position
+	}{
+		{`"a ${1 +} b"`, diagnostic.ExpectedExpression, token.Position{Line: 1, Column: 9, Offset: 8}},
+		{`"a\n" + "b ${x; y}"`, diagnostic.InvalidInterpolation, token.Position{Line: 1, Column: 14, Offset: 13}},
+		{`"${let x = 1}"`, diagnostic.InvalidInterpolation, token.Position{Line: 1, Column: 4, Offset: 3}},
+		{`"${}"`, diagnostic.InvalidInterpolation, token.Position{Line: 1, Column: 4, Offset: 3}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) != 1 {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - expected 1 diagnostic, got %v", tt.input, p.Errors())))
+			continue
+		}
+		if diagnostics[0].Code != tt.expectedCode || diagnostics[0].Span.Start != tt.expectedStart {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong diagnostic. expected %s at %+v, got=%s at %+v",
+				tt.input, tt.expectedCode, tt.expectedStart, diagnostics[0].Code, diagnostics[0].Span.Start)))
+		}
+		if len(program.Statements) != 0 {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - broken statement was kept: %q", tt.input, program.String())))
+		}
+	}
+}
+
+func TestParsingPrefixExpressions(t *testing.T) {
+	prefixTests := []struct {
+		input        string
+		operator     string
+		integerValue int64
+	}{
+		{"!5;", "!", 5},
+		{"-15;", "-", 15},
+	}
+
+	for _, tt := range prefixTests {
+		program := parseInput(t, tt.input)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.PrefixExpression)
+		if !ok {
+			t.Fatalf("stmt is not ast.PrefixExpression. got=%T", stmt.Expression)
+		}
+		if exp.Operator != tt.operator {
+			t.Fatalf("exp.Operator is not '%s'. got=%s", tt.operator, exp.Operator)
+		}
+		testIntegerLiteral(t, exp.Right, tt.integerValue)
+	}
+}
+
+func TestParsingInfixExpressions(t *testing.T) {
+	infixTests := []struct {
+		input      string
+		leftValue  int64
+		operator   string
+		rightValue int64
+	}{
+		{"5 + 5;", 5, "+", 5},
+		{"5 - 5;", 5, "-", 5},
+		{"5 * 5;", 5, "*", 5},
+		{"5 / 5;", 5, "/", 5},
+		{"5 > 5;", 5, ">", 5},
+		{"5 < 5;", 5, "<", 5},
+		{"5 == 5;", 5, "==", 5},
+		{"5 != 5;", 5, "!=", 5},
+	}
+
+	for _, tt := range infixTests {
+		program := parseInput(t, tt.input)
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.InfixExpression)
+		if !ok {
+			t.Fatalf("exp is not ast.InfixExpression. got=%T", stmt.Expression)
+		}
+		testIntegerLiteral(t, exp.Left, tt.leftValue)
+		if exp.Operator != tt.operator {
+			t.Fatalf("exp.Operator is not '%s'. got=%s", tt.operator, exp.Operator)
+		}
+		testIntegerLiteral(t, exp.Right, tt.rightValue)
+	}
+}
+
+func TestOperatorPrecedenceParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-a * b", "((-a) * b)"},
+		{"!-a", "(!(-a))"},
+		{"a + b + c", "((a + b) + c)"},
+		{"a + b - c", "((a + b) - c)"},
+		{"a * b * c", "((a * b) * c)"},
+		{"a * b / c", "((a * b) / c)"},
+		{"a + b / c", "(a + (b / c))"},
+		{"a + b * c + d / e - f", "(((a + (b * c)) + (d / e)) - f)"},
+		{"3 + 4; -5 * 5", "(3 + 4)((-5) * 5)"},
+		{"5 > 4 == 3 < 4", "((5 > 4) == (3 < 4))"},
+		{"5 < 4 != 3 > 4", "((5 < 4) != (3 > 4))"},
+		{"3 + 4 * 5 == 3 * 1 + 4 * 5", "((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))"},
+		{"1 + (2 + 3) + 4", "((1 + (2 + 3)) + 4)"},
+		{"(5 + 5) * 2", "((5 + 5) * 2)"},
+		{"2 / (5 + 5)", "(2 / (5 + 5))"},
+		{"-(5 + 5)", "(-(5 + 5))"},
+		{"true", "true"},
+		{"3 > 5 == false", "((3 > 5) == false)"},
+		{"!(true == true)", "(!(true == true))"},
+		{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
+		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
+		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+		{"-f(x)", "(-f(x))"},
+		{"makeAdder(1)(2)", "makeAdder(1)(2)"},
+		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
+		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"-a[0]", "(-(a[0]))"},
+		{"a[1:2][0]", "((a[1:2])[0])"},
+		{"f(x)[0]", "(f(x)[0])"},
+		{"a <= b == c >= d", "((a <= b) == (c >= d))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"a == b && c != d", "((a == b) && (c != d))"},
+		{"a % b * c", "((a % b) * c)"},
+		{"a + b % c", "(a + (b % c))"},
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"-2 ** 2", "(-(2 ** 2))"},
+		{"2 ** -1", "(2 ** (-1))"},
+		{"a ** b[0]", "(a ** (b[0]))"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a & b == c", "((a & b) == c)"},
+		{"1 << 2 + 3", "(1 << (2 + 3))"},
+		{"a >> 1 & b << 2", "((a >> 1) & (b << 2))"},
+		{"~a & b", "((~a) & b)"},
+		{"x = a || b && c", "(x = (a || (b && c)))"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("expected=%q, got=%q", tt.expected, actual)))
+		}
+	}
+}
+
+func TestIfExpression(t *testing.T) {
+	program := parseInput(t, "if (x < y) { x }")
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+	if exp.Condition.String() != "(x < y)" {
+		t.Errorf("exp.Condition wrong. got=%q", exp.Condition.String())
+	}
+	if len(exp.Consequence.Statements) != 1 {
+		t.Fatalf("consequence is not 1 statement. got=%d", len(exp.Consequence.Statements))
+	}
+	consequence := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+	testIdentifier(t, consequence.Expression, "x")
+	if exp.Alternative != nil {
+		t.Errorf("exp.Alternative was not nil. got=%+v", exp.Alternative)
+	}
+}
+
+func TestIfElseExpression(t *testing.T) {
+	program := parseInput(t, "if (x < y) { x } else { y }")
+	exp := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if exp.Alternative == nil || len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative wrong. got=%+v", exp.Alternative)
+	}
+	alternative := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	testIdentifier(t, alternative.Expression, "y")
+}
+
+func TestElseIfChain(t *testing.T) {
+	program := parseInput(t, "if (a) { 1 } else if (b) { 2 } else if (c) { 3 } else { 4 }")
+	exp := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+
+	conditions := []string{"a", "b", "c"}
+	for i, condition := range conditions {
+		if exp.Condition.String() != condition {
+			t.Fatalf("condition %d wrong. expected=%q, got=%q", i, condition, exp.Condition.String())
+		}
+		testIntegerLiteral(t, exp.Consequence.Statements[0].(*ast.ExpressionStatement).Expression, int64(i+1))
+		if exp.Alternative == nil || len(exp.Alternative.Statements) != 1 {
+			t.Fatalf("alternative %d wrong. got=%+v", i, exp.Alternative)
+		}
+		next, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+		if !ok {
+			testIntegerLiteral(t, exp.Alternative.Statements[0].(*ast.ExpressionStatement).Expression, 4)
+			if i != len(conditions)-1 {
+				t.Fatalf("chain ended early at %d", i)
+			}
+			break
+		}
+		exp = next
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	program := parseInput(t, "fn(x, y) { x + y; }")
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d", len(function.Parameters))
+	}
+	testIdentifier(t, function.Parameters[0], "x")
+	testIdentifier(t, function.Parameters[1], "y")
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("function.Body.Statements has not 1 statement. got=%d", len(function.Body.Statements))
+	}
+	if body := function.Body.Statements[0].String(); body != "(x + y)" {
+		t.Errorf("function body wrong. got=%q", body)
+	}
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{"fn() {};", []string{}},
+		{"fn(x) {};", []string{"x"}},
+		{"fn(x, y, z) {};", []string{"x", "y", "z"}},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Errorf("length parameters wrong. want %d, got=%d", len(tt.expectedParams), len(function.Parameters))
+			continue
+		}
+		for i, ident := range tt.expectedParams {
+			testIdentifier(t, function.Parameters[i], ident)
+		}
+	}
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+	program := parseInput(t, "add(1, 2 * 3, 4 + 5);")
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, exp.Function, "add")
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+	testIntegerLiteral(t, exp.Arguments[0], 1)
+	if exp.Arguments[1].String() != "(2 * 3)" || exp.Arguments[2].String() != "(4 + 5)" {
+		t.Errorf("wrong arguments. got=%v", exp.Arguments)
+	}
+}
+
+func TestArrayLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"[]", []string{}},
+		{"[1]", []string{"1"}},
+		{"[1, 2 * 2, \"three\"]", []string{"1", "(2 * 2)", `"three"`}},
+		{"[[1], []]", []string{"[1]", "[]"}},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		array, ok := stmt.Expression.(*ast.ArrayLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.ArrayLiteral. got=%T", stmt.Expression)
+		}
+		if len(array.Elements) != len(tt.expected) {
+			t.Fatalf("wrong number of elements. expected=%d, got=%d", len(tt.expected), len(array.Elements))
+		}
+		for i, el := range array.Elements {
+			if el.String() != tt.expected[i] {
+				t.Errorf("element %d wrong. expected=%q, got=%q", i, tt.expected[i], el.String())
+			}
+		}
+	}
+}
+
+func TestIndexExpressionParsing(t *testing.T) {
+	program := parseInput(t, "myArray[1 + 1]")
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IndexExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, exp.Left, "myArray")
+	if exp.Index.String() != "(1 + 1)" {
+		t.Errorf("wrong index. expected=%q, got=%q", "(1 + 1)", exp.Index.String())
+	}
+}
+
+func TestSliceExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedLow  string // "" when the bound is left out
+		expectedHigh string
+	}{
+		{"a[1:3]", "1", "3"},
+		{"a[:3]", "", "3"},
+		{"a[1:]", "1", ""},
+		{"a[:]", "", ""},
+		{"a[x:-1]", "x", "(-1)"},
+	}
+
+	bound := func(exp ast.Expression) string {
+		if exp == nil {
+			return ""
+		}
+		return exp.String()
+	}
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("%s: stmt.Expression is not ast.SliceExpression. got=%T", tt.input, stmt.Expression)
+		}
+		testIdentifier(t, exp.Left, "a")
+		if bound(exp.Low) != tt.expectedLow || bound(exp.High) != tt.expectedHigh {
+			t.Errorf("%s: wrong bounds. expected=[%s:%s], got=[%s:%s]", tt.input, tt.expectedLow, tt.expectedHigh, bound(exp.Low), bound(exp.High))
+		}
+	}
+}
+
+func TestHashLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]string // each key's String() to its value's String()
+	}{
+		{"{}", map[string]string{}},
+		{`{"one": 1, "two": 2}`, map[string]string{`"one"`: "1", `"two"`: "2"}},
+		{`{1: true, false: "no"}`, map[string]string{"1": "true", "false": `"no"`}},
+		{`{"sum": 0 + 1, x: 10 / 5,}`, map[string]string{`"sum"`: "(0 + 1)", "x": "(10 / 5)"}},
+		{`{"nested": {"a": [1]}}`, map[string]string{`"nested"`: `{"a": [1]}`}},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("%s: stmt.Expression is not ast.HashLiteral. got=%T", tt.input, stmt.Expression)
+		}
+		if len(hash.Pairs) != len(tt.expected) {
+			t.Fatalf("%s: wrong number of pairs. expected=%d, got=%d", tt.input, len(tt.expected), len(hash.Pairs))
+		}
+		for _, pair := range hash.Pairs {
+			if expected := tt.expected[pair.Key.String()]; pair.Value.String() != expected {
+				t.Errorf("%s: wrong value for %s. expected=%q, got=%q", tt.input, pair.Key, expected, pair.Value.String())
+			}
+		}
+	}
+}
+
+func TestBlockWhereHashExpected(t *testing.T) {
+	p := New(lexer.New("{ let x = 1; }"))
+	p.ParseProgram()
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %v", len(diagnostics), p.Errors())
+	}
+	notes := diagnostics[0].Notes
+	if len(notes) == 0 || notes[len(notes)-1] != "the { at 1:1 starts a hash literal, blocks can only follow if, else and fn" {
+		t.Errorf("missing note about hash literals. got=%q", notes)
+	}
+}
+
+func TestHashLiteralKeepsOrder(t *testing.T) {
+	program := parseInput(t, `{"b": 1, "a": 2, 3: 3}`)
+	if actual := program.String(); actual != `{"b": 1, "a": 2, 3: 3}` {
+		t.Errorf("wrong order. got=%q", actual)
+	}
+}
+
+func TestAssignExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`h["a"] = 1`, `((h["a"]) = 1)`},
+		{`h["a"] = 1 + 2 * 3`, `((h["a"]) = (1 + (2 * 3)))`},
+		{`h[1] = h[2] = 3`, `((h[1]) = ((h[2]) = 3))`},
+		{`h[1] = x == y`, `((h[1]) = (x == y))`},
+		{`x = x + 1`, `(x = (x + 1))`},
+		{`x += 1`, `(x += 1)`},
+		{`x -= y * 2`, `(x -= (y * 2))`},
+		{`a[0] *= 2`, `((a[0]) *= 2)`},
+		{`x /= y = 2`, `(x /= (y = 2))`},
+	}
+
+	for _, tt := range tests {
+		if actual := parseInput(t, tt.input).String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	program := parseInput(t, "const limit = 10; let x = limit;")
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d", len(program.Statements))
+	}
+	for i, constant := range []bool{true, false} {
+		stmt, ok := program.Statements[i].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("statements[%d] is not *ast.LetStatement. got=%T", i, program.Statements[i])
+		}
+		if stmt.Constant() != constant {
+			t.Errorf("statements[%d].Constant() expected=%t, got=%t", i, constant, stmt.Constant())
+		}
+	}
+	if actual := program.String(); actual != "const limit = 10;let x = limit;" {
+		t.Errorf("wrong program. got=%q", actual)
+	}
+}
+
+// Constants can be shadowed by a new binding, and an inner let shadowing a constant can be assigned to
+func TestConstScoping(t *testing.T) {
+	tests := []string{
+		"const x = 1; let x = 2; x = 3;",
+		"const x = 1; let f = fn(x) { x = 2 };",
+		"const x = 1; let f = fn() { let x = 1; x += 1 };",
+		"const i = 1; for (let i = 0; i < 3; i += 1) {}",
+		"const c = 1; for (c in [1, 2]) { c = 3 }",
+	}
+
+	for _, input := range tests {
+		parseInput(t, input)
+	}
+}
+
+func TestLoopParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x += 1 }", "while (x < 10) (x += 1)"},
+		{"for (let i = 0; i < 3; i += 1) { puts(i) }", "for (let i = 0; (i < 3); (i += 1)) puts(i)"},
+		{"for (i = 0; i < 3; i += 1) {}", "for ((i = 0); (i < 3); (i += 1)) "},
+		{"for (;;) { break; }", "for (; ; ) break;"},
+		{"for (x in [1, 2]) { continue }", "for (x in [1, 2]) continue;"},
+		{"outer: while (true) { for (x in xs) { break outer; } }", "outer: while true for (x in xs) break outer;"},
+		{"while (x) {}; x", "while x x"},
+	}
+
+	for _, tt := range tests {
+		if actual := parseInput(t, tt.input).String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	program := parseInput(t, "for (item in items) { item }")
+	stmt, ok := program.Statements[0].(*ast.ForInStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.ForInStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Variable.Value != "item" || stmt.Iterable.String() != "items" || stmt.Label != nil {
+		t.Errorf("wrong for-in loop. got variable=%q iterable=%q label=%v", stmt.Variable, stmt.Iterable, stmt.Label)
+	}
+	if len(stmt.Body.Statements) != 1 {
+		t.Errorf("body expected 1 statement, got %d", len(stmt.Body.Statements))
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	input := "let total = 1 + 2 * x;\nreturn -total;"
+	program := parseInput(t, input)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{program, "let total = 1 + 2 * x;\nreturn -total;"},
+		{program.Statements[0], "let total = 1 + 2 * x;"},
+		{program.Statements[0].(*ast.LetStatement).Name, "total"},
+		{program.Statements[0].(*ast.LetStatement).Value, "1 + 2 * x"},
+		{program.Statements[0].(*ast.LetStatement).Value.(*ast.InfixExpression).Right, "2 * x"},
+		{program.Statements[1], "return -total;"},
+		{program.Statements[1].(*ast.ReturnStatement).ReturnValue, "-total"},
+	}
+
+	callInput := "let f = fn(a) { a };\nf(1, 2)"
+	callProgram := parseInput(t, callInput)
+	tests = append(tests, []struct {
+		node     ast.Node
+		expected string
+	}{
+		{callProgram.Statements[0].(*ast.LetStatement).Value, "fn(a) { a }"},
+		{callProgram.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral).Body, "{ a }"},
+		{callProgram.Statements[1].(*ast.ExpressionStatement).Expression, "f(1, 2)"},
+	}...)
+
+	arrayInput := "[1, 2][0] + xs[1:]"
+	arrayExpression := parseInput(t, arrayInput).Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+	tests = append(tests, []struct {
+		node     ast.Node
+		expected string
+	}{
+		{arrayExpression.Left, "[1, 2][0]"},
+		{arrayExpression.Left.(*ast.IndexExpression).Left, "[1, 2]"},
+		{arrayExpression.Right, "xs[1:]"},
+	}...)
+
+	for i, tt := range tests {
+		span := tt.node.Span()
+		source := input
+		if i >= 10 {
+			source = arrayInput
+		} else if i >= 7 {
+			source = callInput
+		}
+		actual := source[span.Start.Offset:span.End.Offset]
+		if actual != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("tests[%d] - span covers wrong source. expected=%q, got=%q", i, tt.expected, actual)))
+		}
+	}
+
+	returnSpan := program.Statements[1].Span()
+	if returnSpan.Start.Line != 2 || returnSpan.Start.Column != 1 {
+		t.Errorf("return statement starts at wrong position. got=%s", returnSpan.Start)
+	}
+}
+
+func TestMissingPrefixParseFn(t *testing.T) {
+	p := New(lexer.New("let x = ;"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected an error for a missing expression, got none")
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedCode  diagnostic.Code
+		expectedStart token.Position
+		expectedText  string
+	}{
+		{"let x 5;", diagnostic.UnexpectedToken, token.Position{Line: 1, Column: 7, Offset: 6}, "5"},
+		{"let = 5;", diagnostic.UnexpectedToken, token.Position{Line: 1, Column: 5, Offset: 4}, "="},
+		{"let y = 5;\nlet x = ;", diagnostic.ExpectedExpression, token.Position{Line: 2, Column: 9, Offset: 19}, ";"},
+		{"let n = 0x;", diagnostic.MalformedNumber, token.Position{Line: 1, Column: 9, Offset: 8}, "0x"},
+		{"5 @ 5;", diagnostic.IllegalCharacter, token.Position{Line: 1, Column: 3, Offset: 2}, "@"},
+		{`{"a" 1}`, diagnostic.UnexpectedToken, token.Position{Line: 1, Column: 6, Offset: 5}, "1"},
+		{`1 + 2 = 3`, diagnostic.InvalidAssignment, token.Position{Line: 1, Column: 1, Offset: 0}, "1 + 2"},
+		{"const x = 1;\nx += 1", diagnostic.ConstantAssignment, token.Position{Line: 2, Column: 1, Offset: 13}, "x"},
+		{"const x = 1; fn() { x = 2 }", diagnostic.ConstantAssignment, token.Position{Line: 1, Column: 21, Offset: 20}, "x"},
+		{"break;", diagnostic.MisplacedLoopControl, token.Position{Line: 1, Column: 1, Offset: 0}, "break"},
+		{"while (x) { fn() { continue } }", diagnostic.MisplacedLoopControl, token.Position{Line: 1, Column: 20, Offset: 19}, "continue"},
+		{"a: while (x) { break b }", diagnostic.UnknownLabel, token.Position{Line: 1, Column: 22, Offset: 21}, "b"},
+		{"a: let x = 1;", diagnostic.UnexpectedToken, token.Position{Line: 1, Column: 4, Offset: 3}, "let"},
+		{"for (x in xs { x }", diagnostic.UnexpectedToken, token.Position{Line: 1, Column: 14, Offset: 13}, "{"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) == 0 {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - expected diagnostics, got none", tt.input)))
+			continue
+		}
+		d := diagnostics[0]
+		if d.Code != tt.expectedCode || d.Severity != diagnostic.Error {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong diagnostic. expected code %s, got=%s", tt.input, tt.expectedCode, d.Error())))
+		}
+		if d.Span.Start != tt.expectedStart {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - wrong start. expected=%+v, got=%+v", tt.input, tt.expectedStart, d.Span.Start)))
+		}
+		if actual := tt.input[d.Span.Start.Offset:d.Span.End.Offset]; actual != tt.expectedText {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - span covers wrong source. expected=%q, got=%q", tt.input, tt.expectedText, actual)))
+		}
+	}
+}
+
+// An ILLEGAL token is explained once by the lexer, instead of the parser also complaining that it wasn't what it expected
+func TestIllegalTokenDiagnostics(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCode diagnostic.Code
+	}{
+		{"let x = @@;", diagnostic.IllegalCharacter},
+		{"5 @ 5;", diagnostic.IllegalCharacter},
+		{"add(1 $ 2);", diagnostic.IllegalCharacter},
+		{"let x = (1 + 2 ?;", diagnostic.IllegalCharacter},
+		{"outer: ` while (x) {}", diagnostic.IllegalCharacter},
+		{`let s = "never closed`, diagnostic.UnterminatedString},
+		{"[1, 0x, 3]", diagnostic.MalformedNumber},
+		{`len("\q" + 1)`, diagnostic.InvalidEscape},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) != 1 || diagnostics[0].Code != tt.expectedCode {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - expected a single %s diagnostic, got %v", tt.input, tt.expectedCode, diagnostics)))
+		}
+		for _, d := range diagnostics {
+			if strings.Contains(d.Message, token.ILLEGAL) {
+				t.Errorf(util.RedText(fmt.Sprintf("%q - diagnostic mentions ILLEGAL: %s", tt.input, d.Error())))
+			}
+		}
+	}
+
+	// The statements after an illegal token still parse
+	p := New(lexer.New("let a = 1 @ 2; let b = 3;"))
+	program := p.ParseProgram()
+	if program.String() != "let a = 1;let b = 3;" {
+		t.Errorf(util.RedText(fmt.Sprintf("statements around the illegal token parsed wrong. got=%q", program.String())))
+	}
+}
+
+func TestStatementsEndingAtEOF(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5", "let x = 5;"},
+		{"return x + 1", "return (x + 1);"},
+		{"a * b", "(a * b)"},
+		{"let x = 5 // five", "let x = 5;"},
+		{"/* two */ return 2 /* and the rest */", "return 2;"},
+	}
+
+	for _, tt := range tests {
+		program := parseInput(t, tt.input)
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("expected=%q, got=%q", tt.expected, actual)))
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     int
+		expectedStatements string
+	}{
+		// Each broken statement is reported once and the good ones around it still parse
+		{"let = 1; let x 2; let y = 3;", 2, "let y = 3;"},
+		{"let a = (1 + ; let b = 2;", 1, "let b = 2;"},
+		{"let x = \nlet y = 2;", 1, "let y = 2;"},
+		{"return 1; ) let z = z * 2", 1, "return 1;let z = (z * 2);"},
+		// Errors right at the end of the input must not run past EOF
+		{"let", 1, ""},
+		{"let x =", 1, ""},
+		{"return", 1, ""},
+		{"(1 + 2", 1, ""},
+		{"}", 1, ""},
+		// Errors inside a block don't stop the rest of the block being checked
+		{"fn() { let = 1; let y 2; y }", 2, ""},
+		{"let f = fn(x) { x + 1", 1, ""},
+		{"fn(x, ) {}", 1, ""},
+		{"if (x { 1 }; let y = 2;", 1, "let y = 2;"},
+		{"if (x) { 1 } else", 1, ""},
+		{"if (x) { 1 } else if { 2 }", 1, ""},
+		// A block where an expression should be is a broken hash literal, and is skipped as a whole
+		{"{ let x = 1; } let y = 2;", 1, "let y = 2;"},
+		{`let h = {"a": 1, "b" 2}; h`, 1, "h"},
+		// A broken loop is dropped as a whole
+		{"while (x) { let = 1; } let y = 2;", 1, "let y = 2;"},
+		{"for (let i = 0 i < 3; i += 1) {} let y = 2;", 1, "let y = 2;"},
+		{"for (x in xs { x } let y = 2;", 1, "let y = 2;"},
+		{"while (f(x { x; } let y = 2;", 1, "let y = 2;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Diagnostics()) != tt.expectedErrors {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - expected %d errors, got %d: %v", tt.input, tt.expectedErrors, len(p.Diagnostics()), p.Errors())))
+		}
+		if actual := program.String(); actual != tt.expectedStatements {
+			t.Errorf(util.RedText(fmt.Sprintf("%q - expected statements %q, got %q", tt.input, tt.expectedStatements, actual)))
+		}
+	}
+}
+
+// Lexes and parses the input, failing the test if the parser reported any errors
+func parseInput(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	return program
+}
+
+func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
+	integ, ok := il.(*ast.IntegerLiteral)
+	if !ok {
+		t.Errorf("il not *ast.IntegerLiteral. got=%T", il)
+		return false
+	}
+	if integ.Value != value || integ.Big != nil {
+		t.Errorf("integ.Value not %d. got=%d", value, integ.Value)
+		return false
+	}
+	return true
+}
+
+func testIdentifier(t *testing.T, exp ast.Expression, value string) bool {
+	ident, ok := exp.(*ast.Identifier)
+	if !ok {
+		t.Errorf("exp not *ast.Identifier. got=%T", exp)
+		return false
+	}
+	if ident.Value != value || ident.TokenLiteral() != value {
+		t.Errorf("ident.Value not %s. got=%s", value, ident.Value)
+		return false
+	}
+	return true
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	letStmt, ok := s.(*ast.LetStatement)
 	if !ok || letStmt.Name.Value != name || letStmt.Name.TokenLiteral() != name {
@@ -6,6 +6,11 @@ Contains all unique characters and combinations of them used in Clear
 */
 package token
 
+import (
+	"fmt"
+	"sort"
+)
+
 type TokenType string
 
 /*
@@ -17,8 +22,90 @@ type TokenType string
     Ex. Token = { type: INT, value: "123" }
 */
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type     TokenType
+	Literal  string
+	Start    Position      // position of the token's first char
+	End      Position      // position just past the token's last char
+	Leading  []Trivia      // whitespace and comments before the token, only kept by a Lexer made with NewWithTrivia
+	Trailing []Trivia      // whitespace and comments after the token on the same line, only kept by a Lexer made with NewWithTrivia
+	Reason   IllegalReason // why an ILLEGAL token is illegal, empty for every other token
+}
+
+type IllegalReason string
+
+/*
+  The reasons a token can be ILLEGAL
+  The Lexer reports the details as a diagnostic, the reason lets anything holding the token tell the cases apart
+    Ex. Token = { type: ILLEGAL, value: "0x", reason: MALFORMED_NUMBER }
+*/
+const (
+	UNEXPECTED_CHARACTER = "UNEXPECTED_CHARACTER" // a run of characters that can't start any token, like @$
+	UNTERMINATED_STRING  = "UNTERMINATED_STRING"  // a string that is never closed, running to the end of the input
+	MALFORMED_NUMBER     = "MALFORMED_NUMBER"     // a number literal that is written wrong, like 0x, 1e or 12abc
+	INVALID_ESCAPE       = "INVALID_ESCAPE"       // a string containing a malformed escape sequence, like "\q"
+)
+
+type TriviaKind string
+
+const (
+	WHITESPACE    = "WHITESPACE"
+	LINE_COMMENT  = "LINE_COMMENT"  // from '//' to the end of the line
+	BLOCK_COMMENT = "BLOCK_COMMENT" // between '/*' and '*/', which can be nested
+	SHEBANG       = "SHEBANG"       // a '#!' line at the very start of a script, like #!/usr/bin/env clear
+)
+
+/*
+  Trivia is source text that doesn't affect what the program means, like whitespace and comments
+  The parser never sees it, but tools like formatters need it to reproduce the source exactly
+    Ex. Trivia = { Kind: LINE_COMMENT, Text: "// add one" }
+*/
+type Trivia struct {
+	Kind  TriviaKind
+	Text  string
+	Start Position
+	End   Position
+}
+
+/*
+  A position is a single point in the source code
+  Line and Column start at 1 so they can be shown to the user as-is, Offset is the 0-based byte index into the source
+  Column counts characters (runes) rather than bytes, so a column is the same however many bytes the characters before it take up
+*/
+type Position struct {
+	Line   int
+	Column int
+	Offset int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Returns the position reached after reading the given text starting from this position
+func (p Position) Advance(text string) Position {
+	for _, ch := range text {
+		if ch == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	p.Offset += len(text)
+	return p
+}
+
+/*
+  A span is a range of source code, from Start up to (but not including) End
+  Used by tokens and AST nodes alike to point back at the text they came from
+*/
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%s-%s", s.Start, s.End)
 }
 
 const (
@@ -26,8 +113,10 @@ const (
 	EOF     = "EOF"
 
 	// Identifiers + literals
-	IDENT = "IDENT" // indentifier for variables
-	INT   = "INT"
+	IDENT  = "IDENT" // indentifier for variables
+	INT    = "INT"
+	FLOAT  = "FLOAT"
+	STRING = "STRING"
 
 	// Operators
 	ASSIGN   = "="
@@ -36,10 +125,32 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	PERCENT  = "%"
+	POWER    = "**"
+
+	// Bitwise, on the bits of integers
+	AMPERSAND   = "&"
+	PIPE        = "|"
+	CARET       = "^"
+	TILDE       = "~"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
+
+	// Logical, which only evaluate their right side if the left side doesn't already decide the result
+	AND = "&&"
+	OR  = "||"
+
+	// Compound assignment, which applies the operator to the target and the value before storing the result
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
 
 	// Comparison
 	LT     = "<"
 	GT     = ">"
+	LT_EQ  = "<="
+	GT_EQ  = ">="
 	EQ     = "=="
 	NOT_EQ = "!="
 
@@ -50,26 +161,41 @@ const (
 	RPAREN    = ")"
 	LBRACE    = "{"
 	RBRACE    = "}"
+	LBRACKET  = "["
+	RBRACKET  = "]"
+	COLON     = ":"
 
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 )
 
 // This map defines all keywords in the Clear language and maps them to their respective token
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"const":    CONST,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
 }
 
 /*
@@ -85,3 +211,13 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// Returns every keyword in the Clear language in alphabetical order, ex. for tab completion in the REPL
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
@@ -0,0 +1,154 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ajtroup1/interpreters/parsing/lexer"
+	"github.com/ajtroup1/interpreters/parsing/parser"
+	"github.com/ajtroup1/interpreters/parsing/token"
+)
+
+// Lines starting with this are meta-commands for the REPL itself rather than Clear code, like ":help"
+const COMMAND_PREFIX = ":"
+
+/*
+A meta-command the REPL understands
+Usage is how it's typed, shown by :help next to what it does
+*/
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(s *Session, arg string, c *console)
+}
+
+// Filled in by init, since :help lists the commands and so can't be part of their initializer
+var commands []command
+
+func init() {
+	commands = []command{
+		{"help", ":help", "show this list of commands", runHelp},
+		{"tokens", ":tokens", "toggle showing the tokens of each input", runTokens},
+		{"ast", ":ast", "toggle showing the parsed program of each input", runAST},
+		{"type", ":type <expr>", "show the type of an expression's value", runType},
+		{"load", ":load <file.clr>", "evaluate a file into the session", runLoad},
+		{"env", ":env", "list the session's bindings", runEnv},
+		{"reset", ":reset", "forget every binding in the session", runReset},
+	}
+}
+
+// Returns whether the line is a meta-command instead of Clear code
+func isCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), COMMAND_PREFIX)
+}
+
+// Runs the meta-command on the line, which looks like ":name argument"
+func runCommand(s *Session, line string, c *console) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), COMMAND_PREFIX), " ")
+	arg = strings.TrimSpace(arg)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cmd.run(s, arg, c)
+			return
+		}
+	}
+	c.errorf("unknown command %s%s, type :help to see every command\n", COMMAND_PREFIX, name)
+}
+
+func runHelp(s *Session, arg string, c *console) {
+	width := 0
+	for _, cmd := range commands {
+		width = max(width, len(cmd.usage))
+	}
+	for _, cmd := range commands {
+		c.printf("  %-*s  %s\n", width, cmd.usage, cmd.help)
+	}
+}
+
+func runTokens(s *Session, arg string, c *console) {
+	s.showTokens = !s.showTokens
+	c.printf("showing tokens: %s\n", onOff(s.showTokens))
+}
+
+func runAST(s *Session, arg string, c *console) {
+	s.showAST = !s.showAST
+	c.printf("showing the parsed program: %s\n", onOff(s.showAST))
+}
+
+func runType(s *Session, arg string, c *console) {
+	if arg == "" {
+		c.errorf("usage: :type <expr>\n")
+		return
+	}
+	// Only the type is reported, so the expression mustn't change the session or become _
+	evaluated, diagnostics := s.Try(arg)
+	switch {
+	case len(diagnostics) != 0:
+		c.diagnostics(arg, diagnostics)
+	case evaluated == nil:
+		c.printf("no value\n")
+	default:
+		c.printf("%s\n", evaluated.Type())
+	}
+}
+
+func runLoad(s *Session, arg string, c *console) {
+	if arg == "" {
+		c.errorf("usage: :load <file.clr>\n")
+		return
+	}
+	source, err := os.ReadFile(arg)
+	if err != nil {
+		c.errorf("%s\n", err)
+		return
+	}
+	if _, diagnostics := s.Eval(string(source)); len(diagnostics) != 0 {
+		c.diagnostics(string(source), diagnostics)
+		return
+	}
+	c.printf("loaded %s\n", arg)
+}
+
+func runEnv(s *Session, arg string, c *console) {
+	names := s.env.Names()
+	if len(names) == 0 {
+		c.printf("nothing is bound yet\n")
+		return
+	}
+	for _, name := range names {
+		value, _ := s.env.Get(name)
+		c.printf("%s = %s\n", name, value.Inspect())
+	}
+}
+
+func runReset(s *Session, arg string, c *console) {
+	s.Reset()
+	c.printf("session reset\n")
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// Writes every token of the source on its own line, with where it starts
+func writeTokens(out io.Writer, source string) {
+	l := lexer.New(source)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(out, "%-7s %-9s %q\n", tok.Start, tok.Type, tok.Literal)
+	}
+}
+
+// Writes every statement the source parses into on its own line, in the parser's fully parenthesised form
+func writeAST(out io.Writer, source string) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	for _, stmt := range program.Statements {
+		fmt.Fprintln(out, stmt.String())
+	}
+}
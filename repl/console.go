@@ -0,0 +1,43 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
+)
+
+/*
+Where the REPL writes to
+Results and anything asked for go to out, errors go to errOut, and each is colored only if it takes color
+Nothing in the REPL writes to the process's stdout or stderr directly, so it can be embedded or driven by tests
+*/
+type console struct {
+	out      io.Writer
+	errOut   io.Writer
+	color    bool // whether out takes color
+	errColor bool // whether errOut takes color
+}
+
+func newConsole(out, errOut io.Writer) *console {
+	return &console{out: out, errOut: errOut, color: colorEnabled(out), errColor: colorEnabled(errOut)}
+}
+
+func (c *console) printf(format string, a ...interface{}) {
+	fmt.Fprintf(c.out, format, a...)
+}
+
+func (c *console) errorf(format string, a ...interface{}) {
+	fmt.Fprintf(c.errOut, format, a...)
+}
+
+// Shows a value on its own line
+func (c *console) value(obj object.Object) {
+	fmt.Fprintln(c.out, formatValue(obj, c.color))
+}
+
+// Shows diagnostics, rendered against the source they're about
+func (c *console) diagnostics(source string, diagnostics []diagnostic.Diagnostic) {
+	fmt.Fprint(c.errOut, diagnostic.RenderAll(source, diagnostics, c.errColor))
+}
@@ -0,0 +1,50 @@
+package repl
+
+import (
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
+	"github.com/ajtroup1/interpreters/parsing/lexer"
+	"github.com/ajtroup1/interpreters/parsing/parser"
+	"github.com/ajtroup1/interpreters/parsing/token"
+)
+
+/*
+Decides whether the input typed so far forms complete statements, or whether the REPL should keep reading lines
+Input is incomplete when:
+	- a '(', '{' or '[' hasn't been closed yet
+	- a string or block comment is still open
+	- the only problems the parser found are at the very end of the input, like "let x =" or "1 +"
+Input with errors anywhere else is complete, since more lines can't fix it, and it's submitted so the errors get shown
+*/
+func isComplete(input string) bool {
+	l := lexer.New(input)
+	depth := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		}
+	}
+	if depth > 0 {
+		return false
+	}
+	for _, d := range l.Diagnostics() {
+		if d.Code == diagnostic.UnterminatedString || d.Code == diagnostic.UnterminatedComment {
+			return false
+		}
+	}
+
+	p := parser.New(lexer.New(input))
+	p.ParseProgram()
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) == 0 {
+		return true
+	}
+	for _, d := range diagnostics {
+		if d.Span.Start.Offset < len(input) {
+			return true
+		}
+	}
+	return false
+}
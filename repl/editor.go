@@ -0,0 +1,447 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Returned by ReadLine when the line was abandoned with Ctrl-C
+var errInterrupted = errors.New("interrupted")
+
+// Where the REPL gets its input from, one line at a time
+type lineReader interface {
+	// Shows the prompt and reads a line, without its newline. Returns io.EOF once there is no more input
+	ReadLine(prompt string) (string, error)
+}
+
+/*
+Picks how to read input
+A terminal gets the line editor, which highlights the line as it's typed if out takes color, anything else (like a pipe or a file) is read as plain lines
+*/
+func newLineReader(in io.Reader, out io.Writer, complete func(prefix string) []string) lineReader {
+	if f, ok := in.(*os.File); ok && isTerminal(f.Fd()) {
+		e := newEditor(f, out, loadHistory(defaultHistoryPath()), complete)
+		if colorEnabled(out) {
+			e.highlight = highlight
+		}
+		return &terminalReader{fd: f.Fd(), editor: e}
+	}
+	return &plainReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+// Reads lines as they are, for input that isn't typed in a terminal
+type plainReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// Reads lines with the editor, switching the terminal into raw mode only while a line is being typed
+type terminalReader struct {
+	fd     uintptr
+	editor *editor
+}
+
+func (r *terminalReader) ReadLine(prompt string) (string, error) {
+	restore, err := makeRaw(r.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	return r.editor.ReadLine(prompt)
+}
+
+// Keys the editor handles, as the bytes a terminal in raw mode sends for them
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyNewline   = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+/*
+Keys that arrive as escape sequences, like the arrow keys
+They are given values past the end of Unicode so they can't be mistaken for typed chars
+*/
+const (
+	keyUp = unicode.MaxRune + 1 + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyWordLeft
+	keyWordRight
+	keyUnknown
+)
+
+/*
+An interactive line editor for terminals in raw mode
+Supports moving the cursor, editing anywhere in the line, going through history, reverse search (Ctrl-R) and tab completion
+	Ex. typing "ret" then Tab completes to "return", since nothing else starts with "ret"
+*/
+type editor struct {
+	in        *bufio.Reader
+	out       io.Writer
+	history   *history
+	complete  func(prefix string) []string // returns every completion for the word being typed
+	highlight func(line string) string     // colors the line as it's drawn, nil to draw it plain
+}
+
+func newEditor(in io.Reader, out io.Writer, h *history, complete func(prefix string) []string) *editor {
+	return &editor{in: bufio.NewReader(in), out: out, history: h, complete: complete}
+}
+
+// The line being edited
+type lineState struct {
+	prompt  string
+	buf     []rune
+	cursor  int    // index into buf the next char is inserted at
+	index   int    // the history entry being shown, len(history.entries) for the line being typed
+	typed   string // the line being typed, kept while going through history
+	pending rune   // a key that still needs handling after reverse search ended, 0 for none
+}
+
+/*
+Reads and edits a line until Enter is pressed
+Ctrl-D on an empty line ends the input with io.EOF, Ctrl-C abandons the line with errInterrupted
+*/
+func (e *editor) ReadLine(prompt string) (string, error) {
+	s := &lineState{prompt: prompt, index: len(e.history.entries)}
+	fmt.Fprint(e.out, prompt)
+	for {
+		key, err := e.nextKey(s)
+		if err != nil {
+			if len(s.buf) == 0 {
+				return "", err
+			}
+			key = keyEnter
+		}
+
+		switch key {
+		case keyEnter, keyNewline:
+			fmt.Fprint(e.out, "\n")
+			line := string(s.buf)
+			e.history.add(line)
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(s.buf) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+			s.delete(s.cursor, s.cursor+1)
+		case keyDelete:
+			s.delete(s.cursor, s.cursor+1)
+		case keyBackspace, keyCtrlH:
+			s.delete(s.cursor-1, s.cursor)
+		case keyCtrlA, keyHome:
+			s.cursor = 0
+		case keyCtrlE, keyEnd:
+			s.cursor = len(s.buf)
+		case keyCtrlB, keyLeft:
+			s.cursor = max(s.cursor-1, 0)
+		case keyCtrlF, keyRight:
+			s.cursor = min(s.cursor+1, len(s.buf))
+		case keyWordLeft:
+			s.cursor = s.wordStart()
+		case keyWordRight:
+			s.cursor = s.wordEnd()
+		case keyCtrlK:
+			s.delete(s.cursor, len(s.buf))
+		case keyCtrlU:
+			s.delete(0, s.cursor)
+		case keyCtrlW:
+			s.delete(s.wordStart(), s.cursor)
+		case keyCtrlP, keyUp:
+			e.showHistory(s, s.index-1)
+		case keyCtrlN, keyDown:
+			e.showHistory(s, s.index+1)
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyTab:
+			e.completeWord(s)
+		case keyCtrlR:
+			e.reverseSearch(s)
+		default:
+			if key <= unicode.MaxRune && unicode.IsPrint(key) {
+				s.insert(string(key))
+			}
+		}
+		e.redraw(s)
+	}
+}
+
+// Returns the next key, decoding escape sequences like the arrow keys
+func (e *editor) nextKey(s *lineState) (rune, error) {
+	if s.pending != 0 {
+		key := s.pending
+		s.pending = 0
+		return key, nil
+	}
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+
+	r, _, err = e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch r {
+	case 'b':
+		return keyWordLeft, nil
+	case 'f':
+		return keyWordRight, nil
+	case '[', 'O':
+	default:
+		return keyUnknown, nil
+	}
+
+	// CSI sequences are some optional numbers followed by a final letter or '~', ex. "\x1b[A" or "\x1b[3~"
+	var params strings.Builder
+	for {
+		r, _, err = e.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if ('0' <= r && r <= '9') || r == ';' {
+			params.WriteRune(r)
+			continue
+		}
+		break
+	}
+	switch r {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	case '~':
+		switch params.String() {
+		case "1", "7":
+			return keyHome, nil
+		case "4", "8":
+			return keyEnd, nil
+		case "3":
+			return keyDelete, nil
+		}
+	}
+	return keyUnknown, nil
+}
+
+// Redraws the whole line and puts the terminal's cursor back where the editor's cursor is
+func (e *editor) redraw(s *lineState) {
+	line := string(s.buf)
+	if e.highlight != nil {
+		line = e.highlight(line)
+	}
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", s.prompt, line)
+	if back := len(s.buf) - s.cursor; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
+
+// Replaces the line with the history entry at index, where len(history.entries) is the line that was being typed
+func (e *editor) showHistory(s *lineState, index int) {
+	if index < 0 || index > len(e.history.entries) {
+		return
+	}
+	if s.index == len(e.history.entries) {
+		s.typed = string(s.buf)
+	}
+	s.index = index
+	if index == len(e.history.entries) {
+		s.set(s.typed)
+	} else {
+		s.set(e.history.entries[index])
+	}
+}
+
+/*
+Completes the word before the cursor
+A single match is filled in, several matches are filled in as far as they agree and listed below the line if they don't agree any further
+*/
+func (e *editor) completeWord(s *lineState) {
+	start := s.cursor
+	for start > 0 && isWordRune(s.buf[start-1]) {
+		start--
+	}
+	prefix := string(s.buf[start:s.cursor])
+	if prefix == "" || e.complete == nil {
+		return
+	}
+	matches := e.complete(prefix)
+	if len(matches) == 0 {
+		fmt.Fprint(e.out, "\a")
+		return
+	}
+	common := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, common) {
+			_, size := utf8.DecodeLastRuneInString(common)
+			common = common[:len(common)-size]
+		}
+	}
+	if len(common) > len(prefix) {
+		s.insert(common[len(prefix):])
+		return
+	}
+	if len(matches) > 1 {
+		fmt.Fprintf(e.out, "\n%s\n", strings.Join(matches, "  "))
+	}
+}
+
+/*
+Searches backwards through history for entries containing what's typed, like Ctrl-R in a shell
+Ctrl-R again finds an older match, Ctrl-G or Ctrl-C gives up and leaves the line as it was
+Any other key takes the match into the line and is then handled as usual, so Enter runs the match straight away
+*/
+func (e *editor) reverseSearch(s *lineState) {
+	query := ""
+	found := s.index
+	failing := false
+	match := string(s.buf)
+	search := func(from int) {
+		for i := min(from, len(e.history.entries)-1); i >= 0; i-- {
+			if strings.Contains(e.history.entries[i], query) {
+				found, match, failing = i, e.history.entries[i], false
+				return
+			}
+		}
+		failing = true
+	}
+
+	for {
+		label := "reverse-i-search"
+		if failing {
+			label = "failing " + label
+		}
+		fmt.Fprintf(e.out, "\r(%s)`%s': %s\x1b[K", label, query, match)
+
+		key, err := e.nextKey(s)
+		if err != nil {
+			return
+		}
+		switch {
+		case key == keyCtrlR:
+			search(found - 1)
+		case key == keyBackspace || key == keyCtrlH:
+			if query != "" {
+				runes := []rune(query)
+				query = string(runes[:len(runes)-1])
+				search(len(e.history.entries) - 1)
+			}
+		case key == keyCtrlG || key == keyCtrlC:
+			return
+		case key <= unicode.MaxRune && unicode.IsPrint(key):
+			query += string(key)
+			search(found)
+		default:
+			if query != "" && !failing {
+				s.index = found
+				s.set(match)
+			}
+			s.pending = key
+			return
+		}
+	}
+}
+
+// Inserts text at the cursor, leaving the cursor after it
+func (s *lineState) insert(text string) {
+	runes := []rune(text)
+	s.buf = append(s.buf[:s.cursor], append(runes, s.buf[s.cursor:]...)...)
+	s.cursor += len(runes)
+}
+
+// Deletes the chars between from and to, ignoring any part of that range outside the line
+func (s *lineState) delete(from, to int) {
+	from, to = max(from, 0), min(to, len(s.buf))
+	if from >= to {
+		return
+	}
+	s.buf = append(s.buf[:from], s.buf[to:]...)
+	if s.cursor > to {
+		s.cursor -= to - from
+	} else if s.cursor > from {
+		s.cursor = from
+	}
+}
+
+// Replaces the whole line, leaving the cursor at the end
+func (s *lineState) set(line string) {
+	s.buf = []rune(line)
+	s.cursor = len(s.buf)
+}
+
+// Returns where the word before the cursor starts, skipping any spaces right before the cursor
+func (s *lineState) wordStart() int {
+	i := s.cursor
+	for i > 0 && !isWordRune(s.buf[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(s.buf[i-1]) {
+		i--
+	}
+	return i
+}
+
+// Returns where the word after the cursor ends
+func (s *lineState) wordEnd() int {
+	i := s.cursor
+	for i < len(s.buf) && !isWordRune(s.buf[i]) {
+		i++
+	}
+	for i < len(s.buf) && isWordRune(s.buf[i]) {
+		i++
+	}
+	return i
+}
+
+// Words are made of the chars identifiers are made of
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
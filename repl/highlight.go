@@ -0,0 +1,126 @@
+package repl
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/lexer"
+	"github.com/ajtroup1/interpreters/parsing/token"
+	"github.com/ajtroup1/interpreters/util"
+)
+
+/*
+Returns whether output written to out should be colored
+Only terminals get color, and never when the NO_COLOR environment variable is set (see https://no-color.org)
+*/
+func colorEnabled(out io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := out.(*os.File)
+	return ok && isTerminal(f.Fd())
+}
+
+// Operators are colored, other punctuation like parentheses and commas is left alone
+var operators = map[token.TokenType]bool{
+	token.ASSIGN:          true,
+	token.PLUS_ASSIGN:     true,
+	token.MINUS_ASSIGN:    true,
+	token.ASTERISK_ASSIGN: true,
+	token.SLASH_ASSIGN:    true,
+	token.PLUS:            true,
+	token.MINUS:           true,
+	token.BANG:            true,
+	token.ASTERISK:        true,
+	token.SLASH:           true,
+	token.PERCENT:         true,
+	token.POWER:           true,
+	token.AMPERSAND:       true,
+	token.PIPE:            true,
+	token.CARET:           true,
+	token.TILDE:           true,
+	token.SHIFT_LEFT:      true,
+	token.SHIFT_RIGHT:     true,
+	token.AND:             true,
+	token.OR:              true,
+	token.LT:              true,
+	token.GT:              true,
+	token.LT_EQ:           true,
+	token.GT_EQ:           true,
+	token.EQ:              true,
+	token.NOT_EQ:          true,
+}
+
+/*
+Colors source code by what each token is, using the real lexer so the colors always agree with how Clear reads the code
+The text itself is left exactly as it was, including whitespace and comments
+	Ex. keywords are magenta, numbers yellow, strings green and anything ILLEGAL red
+*/
+func highlight(source string) string {
+	var out strings.Builder
+	writeTrivia := func(trivia []token.Trivia) {
+		for _, t := range trivia {
+			if t.Kind == token.WHITESPACE {
+				out.WriteString(t.Text)
+			} else {
+				out.WriteString(util.GrayText(t.Text))
+			}
+		}
+	}
+
+	l := lexer.NewWithTrivia(source)
+	for {
+		tok := l.NextToken()
+		writeTrivia(tok.Leading)
+		if tok.Type == token.EOF {
+			break
+		}
+		text := source[tok.Start.Offset:tok.End.Offset]
+		if colorize := tokenColor(tok); colorize != nil {
+			text = colorize(text)
+		}
+		out.WriteString(text)
+		writeTrivia(tok.Trailing)
+	}
+	return out.String()
+}
+
+// Returns the color function for the token's class, or nil if it isn't colored
+func tokenColor(tok token.Token) func(string) string {
+	switch {
+	case tok.Type == token.ILLEGAL:
+		return util.RedText
+	case tok.Type == token.IDENT:
+		return util.CyanText
+	case tok.Type == token.INT || tok.Type == token.FLOAT:
+		return util.YellowText
+	case tok.Type == token.STRING:
+		return util.GreenText
+	case operators[tok.Type]:
+		return util.BlueText
+	case token.LookupIdent(tok.Literal) == tok.Type:
+		return util.MagentaText
+	}
+	return nil
+}
+
+// Formats a value to show as a result, colored by its type if color is true
+func formatValue(obj object.Object, color bool) string {
+	text := obj.Inspect()
+	if !color {
+		return text
+	}
+	switch obj.Type() {
+	case object.INTEGER_OBJ, object.FLOAT_OBJ:
+		return util.YellowText(text)
+	case object.STRING_OBJ:
+		return util.GreenText(text)
+	case object.BOOLEAN_OBJ, object.NULL_OBJ:
+		return util.MagentaText(text)
+	case object.FUNCTION_OBJ:
+		return util.CyanText(text)
+	}
+	return text
+}
@@ -0,0 +1,76 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The file in the user's home directory that history is kept in between sessions
+const HISTORY_FILE = ".clear_history"
+
+// Only this many of the most recent lines are loaded back from the history file
+const MAX_HISTORY = 1000
+
+/*
+The lines entered in interactive sessions, oldest first
+Every line added is also appended to the history file, so it's still there the next time the REPL starts
+*/
+type history struct {
+	entries []string
+	path    string // "" if the history isn't saved
+}
+
+// Returns the path of the history file in the user's home directory, or "" if there is no home directory
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, HISTORY_FILE)
+}
+
+/*
+Loads the history saved at path
+A missing or unreadable file just means there's no history yet
+*/
+func loadHistory(path string) *history {
+	h := &history{path: path}
+	if path == "" {
+		return h
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.entries = append(h.entries, scanner.Text())
+	}
+	if len(h.entries) > MAX_HISTORY {
+		h.entries = h.entries[len(h.entries)-MAX_HISTORY:]
+	}
+	return h
+}
+
+/*
+Remembers a line that was entered
+Blank lines and repeats of the line just before are skipped, since they'd only get in the way when going back through history
+*/
+func (h *history) add(line string) {
+	if strings.TrimSpace(line) == "" || (len(h.entries) != 0 && h.entries[len(h.entries)-1] == line) {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(line + "\n")
+}
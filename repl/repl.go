@@ -1,28 +1,68 @@
 package repl
 
 import (
-	"bufio"
-	"fmt"
 	"io"
-
-	"github.com/ajtroup1/interpreters/parsing/lexer"
-	"github.com/ajtroup1/interpreters/parsing/token"
+	"strings"
 )
 
 const PROMPT = ">> "
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+// Shown instead of PROMPT while the input so far is incomplete, like a function body that hasn't been closed
+const CONTINUATION_PROMPT = ".. "
+
+/*
+Reads input line by line, evaluating it as soon as it forms complete statements
+Until then, lines are collected under a continuation prompt, so a function can be typed across several lines
+Entering an empty line while continuing submits the input as it is, so a mistake can't leave the REPL stuck waiting
+Everything entered is evaluated in one Session, so later inputs can use what earlier ones defined
+Lines starting with ':' are meta-commands instead, see :help
+In a terminal lines are typed with a line editor that has history and tab completion, Ctrl-C throws away the input so far
+Prompts and values are written to out and errors to errOut, each colored if it's a terminal, unless NO_COLOR is set
+*/
+func Start(in io.Reader, out, errOut io.Writer) {
+	session := NewSession()
+	console := newConsole(out, errOut)
+	lines := newLineReader(in, out, session.Complete)
+	var input strings.Builder
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		prompt := PROMPT
+		if input.Len() != 0 {
+			prompt = CONTINUATION_PROMPT
+		}
+		line, err := lines.ReadLine(prompt)
+		if err == errInterrupted {
+			input.Reset()
+			continue
+		}
+		if err != nil {
 			return
 		}
-		line := scanner.Text()
-		l := lexer.New(line)
-		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
-			fmt.Printf("%+v\n", tok)
+		if input.Len() == 0 && isCommand(line) {
+			runCommand(session, line, console)
+			continue
+		}
+
+		continuing := input.Len() != 0
+		input.WriteString(line + "\n")
+		if !isComplete(input.String()) && !(continuing && strings.TrimSpace(line) == "") {
+			continue
+		}
+		source := input.String()
+		input.Reset()
+
+		if session.showTokens {
+			writeTokens(out, source)
+		}
+		if session.showAST {
+			writeAST(out, source)
+		}
+		evaluated, diagnostics := session.Eval(source)
+		if len(diagnostics) != 0 {
+			console.diagnostics(source, diagnostics)
+			continue
+		}
+		if evaluated != nil {
+			console.value(evaluated)
 		}
 	}
 }
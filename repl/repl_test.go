@@ -0,0 +1,500 @@
+package repl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
+	"github.com/ajtroup1/interpreters/util"
+)
+
+func TestIsComplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"", true},
+		{"5 + 5\n", true},
+		{"let x = 5;\n", true},
+		{"let add = fn(a, b) {\n", false},
+		{"let add = fn(a, b) {\n  a + b\n", false},
+		{"let add = fn(a, b) {\n  a + b\n};\n", true},
+		{"add(1,\n", false},
+		{"add(1,\n2)\n", true},
+		{"let a = [1,\n", false},
+		{"let a = [1,\n2];\n", true},
+		{"if (x) {\n 1\n} else {\n", false},
+		{"let x =\n", false},
+		{"1 +\n", false},
+		{"\"unterminated\n", false},
+		{"/* still\n in a comment\n", false},
+		// Errors that more input can't fix are submitted right away
+		{"let = 5;\n", true},
+		{"1 + ) + {\n", true},
+		{"}\n", true},
+	}
+
+	for _, tt := range tests {
+		if actual := isComplete(tt.input); actual != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("isComplete(%q) wrong. expected=%t, got=%t", tt.input, tt.expected, actual)))
+		}
+	}
+}
+
+func TestSessionKeepsState(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // "" when nothing should be shown
+	}{
+		{"let x = 5;", ""},
+		{"x", "5"},
+		{"let double = fn(n) { n * 2 };", ""},
+		{"double(x)", "10"},
+		{"_ + 1", "11"},
+		{"let y = x + true;", "error"},
+		{"let counter = fn() { x };", ""},
+		{"let x = 7; counter()", "7"},
+		{"", ""},
+	}
+
+	session := NewSession()
+	for _, tt := range tests {
+		evaluated, diagnostics := session.Eval(tt.input)
+		switch {
+		case tt.expected == "error":
+			if len(diagnostics) == 0 {
+				t.Errorf(util.RedText(fmt.Sprintf("input %q: expected an error, got none", tt.input)))
+			}
+		case len(diagnostics) != 0:
+			t.Errorf(util.RedText(fmt.Sprintf("input %q: unexpected diagnostics: %v", tt.input, diagnostics)))
+		case tt.expected == "":
+			if evaluated != nil {
+				t.Errorf(util.RedText(fmt.Sprintf("input %q: expected no value, got %s", tt.input, evaluated.Inspect())))
+			}
+		case evaluated == nil:
+			t.Errorf(util.RedText(fmt.Sprintf("input %q: expected %s, got no value", tt.input, tt.expected)))
+		case evaluated.Inspect() != tt.expected:
+			t.Errorf(util.RedText(fmt.Sprintf("input %q: expected %s, got %s", tt.input, tt.expected, evaluated.Inspect())))
+		}
+	}
+}
+
+func TestSessionRuntimeErrorDiagnostic(t *testing.T) {
+	_, diagnostics := NewSession().Eval("let a = 1;\na + missing")
+	if len(diagnostics) != 1 {
+		t.Fatalf(util.RedText(fmt.Sprintf("expected 1 diagnostic, got %d", len(diagnostics))))
+	}
+	d := diagnostics[0]
+	if d.Code != diagnostic.RuntimeError || d.Message != "identifier not found: missing" {
+		t.Errorf(util.RedText(fmt.Sprintf("wrong diagnostic: %s", d.Error())))
+	}
+	if d.Span.Start.String() != "2:5" {
+		t.Errorf(util.RedText(fmt.Sprintf("wrong position. expected=2:5, got=%s", d.Span.Start)))
+	}
+}
+
+func TestCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.clr")
+	if err := os.WriteFile(path, []byte("let square = fn(n) { n * n };\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{":env", "nothing is bound yet\n"},
+		{":load " + path, "loaded " + path + "\n"},
+		{":type square(3)", "INTEGER\n"},
+		{":type \"hi\"", "STRING\n"},
+		{":type let x = 1;", "no value\n"},
+		{":type", "usage: :type <expr>\n"},
+		// Asking for a type neither binds anything nor sets _
+		{":env", "square = fn(n) {\n(n * n)\n}\n"},
+		{":tokens", "showing tokens: on\n"},
+		{":tokens", "showing tokens: off\n"},
+		{":ast", "showing the parsed program: on\n"},
+		{":reset", "session reset\n"},
+		{":env", "nothing is bound yet\n"},
+		{":nope", "unknown command :nope, type :help to see every command\n"},
+	}
+
+	session := NewSession()
+	for _, tt := range tests {
+		var out bytes.Buffer
+		runCommand(session, tt.line, &console{out: &out, errOut: &out})
+		if out.String() != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: wrong output. expected=%q, got=%q", tt.line, tt.expected, out.String())))
+		}
+	}
+	if !session.showAST || session.showTokens {
+		t.Errorf(util.RedText(fmt.Sprintf("wrong toggles. showAST=%t, showTokens=%t", session.showAST, session.showTokens)))
+	}
+}
+
+func TestWriteTokensAndAST(t *testing.T) {
+	var out bytes.Buffer
+	writeTokens(&out, "let x = -1;")
+	writeAST(&out, "let x = -1;")
+	expected := `1:1     LET       "let"
+1:5     IDENT     "x"
+1:7     =         "="
+1:9     -         "-"
+1:10    INT       "1"
+1:11    ;         ";"
+let x = (-1);
+`
+	if out.String() != expected {
+		t.Errorf(util.RedText(fmt.Sprintf("wrong output. expected=%q, got=%q", expected, out.String())))
+	}
+}
+
+func TestEditor(t *testing.T) {
+	complete := func(prefix string) []string {
+		var matches []string
+		for _, word := range []string{"else", "false", "let", "letter", "lettuce", "return"} {
+			if strings.HasPrefix(word, prefix) {
+				matches = append(matches, word)
+			}
+		}
+		return matches
+	}
+	tests := []struct {
+		name     string
+		history  []string
+		keys     string
+		expected string
+	}{
+		{"typing", nil, "let x = 1;\r", "let x = 1;"},
+		{"arrows", nil, "bc\x1b[D\x1b[Da\x1b[C\x1b[CX\r", "abcX"},
+		{"home and end", nil, "b\x01a\x05c\r", "abc"},
+		{"home and end sequences", nil, "b\x1b[Ha\x1b[Fc\x1b[1~_\x1b[4~_\r", "_abc_"},
+		{"backspace", nil, "abd\x7f\x7fc\r", "ac"},
+		{"delete", nil, "xabc\x01\x1b[3~\r", "abc"},
+		{"kill to end", nil, "abcdef\x01\x06\x06\x0b\r", "ab"},
+		{"kill to start", nil, "abcdef\x02\x02\x15\r", "ef"},
+		{"delete word", nil, "let value  \x17\r", "let "},
+		{"word motions", nil, "one two\x1bbX\x1bf!\r", "one Xtwo!"},
+		{"history", []string{"first", "second"}, "\x1b[A\x1b[A\r", "first"},
+		{"history keeps the typed line", []string{"first"}, "typed\x10\x0e\r", "typed"},
+		{"history stops at the oldest entry", []string{"first"}, "\x1b[A\x1b[A\x1b[A\r", "first"},
+		{"complete a unique match", nil, "ret\t 1\r", "return 1"},
+		{"complete a common prefix", nil, "le\t\r", "let"},
+		{"complete in the middle of a line", nil, "f(x) el\x01\x06\x06\x06\x06\x06\x06\x06\t\r", "f(x) else"},
+		{"no completions", nil, "zz\t\r", "zz"},
+		{"reverse search", []string{"let a = 1;", "a + 1", "let b = 2;"}, "\x12let\r", "let b = 2;"},
+		{"reverse search older match", []string{"let a = 1;", "a + 1", "let b = 2;"}, "\x12let\x12\r", "let a = 1;"},
+		{"reverse search then edit", []string{"a + 1"}, "\x12+ 1\x05 + 2\r", "a + 1 + 2"},
+		{"reverse search cancelled", []string{"a + 1"}, "kept\x12a\x07\r", "kept"},
+		{"unicode", nil, "héllo\x7f\x7fo\r", "hélo"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := newEditor(strings.NewReader(tt.keys), &out, &history{entries: tt.history}, complete)
+		line, err := e.ReadLine(PROMPT)
+		if err != nil {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: unexpected error: %s", tt.name, err)))
+			continue
+		}
+		if line != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: wrong line. expected=%q, got=%q", tt.name, tt.expected, line)))
+		}
+	}
+}
+
+func TestEditorEndsInput(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected error
+	}{
+		{"\x04", io.EOF},
+		{"", io.EOF},
+		{"abc\x03", errInterrupted},
+	}
+
+	for _, tt := range tests {
+		e := newEditor(strings.NewReader(tt.keys), io.Discard, &history{}, nil)
+		if _, err := e.ReadLine(PROMPT); err != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("keys %q: wrong error. expected=%v, got=%v", tt.keys, tt.expected, err)))
+		}
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HISTORY_FILE)
+	h := loadHistory(path)
+	for _, line := range []string{"let x = 1;", "", "x", "x", "x + 1"} {
+		h.add(line)
+	}
+
+	loaded := loadHistory(path)
+	expected := []string{"let x = 1;", "x", "x + 1"}
+	if !slices.Equal(loaded.entries, expected) {
+		t.Errorf(util.RedText(fmt.Sprintf("wrong history. expected=%q, got=%q", expected, loaded.entries)))
+	}
+}
+
+func TestSessionComplete(t *testing.T) {
+	session := NewSession()
+	session.Eval("let range = 1; let reduce = fn() {}; let flag = true;")
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"re", []string{"reduce", "return"}},
+		{"f", []string{"false", "flag", "fn", "for"}},
+		{"zzz", nil},
+	}
+
+	for _, tt := range tests {
+		if actual := session.Complete(tt.prefix); !slices.Equal(actual, tt.expected) {
+			t.Errorf(util.RedText(fmt.Sprintf("Complete(%q) wrong. expected=%q, got=%q", tt.prefix, tt.expected, actual)))
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5;", util.MagentaText("let") + " " + util.CyanText("x") + " " + util.BlueText("=") + " " + util.YellowText("5") + ";"},
+		{"f(1.5, \"s\")", util.CyanText("f") + "(" + util.YellowText("1.5") + ", " + util.GreenText(`"s"`) + ")"},
+		{"!true // done", util.BlueText("!") + util.MagentaText("true") + " " + util.GrayText("// done")},
+		{"a @ b", util.CyanText("a") + " " + util.RedText("@") + " " + util.CyanText("b")},
+		{"  \"open", "  " + util.RedText(`"open`)},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if actual := highlight(tt.input); actual != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("highlight(%q) wrong. expected=%q, got=%q", tt.input, tt.expected, actual)))
+		}
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	if colorEnabled(&bytes.Buffer{}) {
+		t.Errorf(util.RedText("output that isn't a terminal should not be colored"))
+	}
+	t.Setenv("NO_COLOR", "1")
+	if colorEnabled(os.Stdout) {
+		t.Errorf(util.RedText("NO_COLOR should turn color off"))
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	session := NewSession()
+	tests := []struct {
+		input    string
+		color    bool
+		expected string
+	}{
+		{"1 + 1", false, "2"},
+		{"1 + 1", true, util.YellowText("2")},
+		{`"hi"`, true, util.GreenText("hi")},
+		{"1 < 2", true, util.MagentaText("true")},
+	}
+
+	for _, tt := range tests {
+		evaluated, _ := session.Eval(tt.input)
+		if actual := formatValue(evaluated, tt.color); actual != tt.expected {
+			t.Errorf(util.RedText(fmt.Sprintf("%s: wrong value. expected=%q, got=%q", tt.input, tt.expected, actual)))
+		}
+	}
+}
+
+/*
+Runs a REPL session from a transcript of it, as it would look in a terminal, and checks the output matches exactly
+Lines starting with a prompt are typed in, everything else is what the REPL should print in reply
+Since typed input isn't echoed outside a terminal, only the prompt of those lines is expected in the output
+The session always ends at one last PROMPT, when the input runs out
+Errors are checked separately against expectedErr
+*/
+func testTranscript(t *testing.T, name, transcript, expectedErr string) {
+	t.Helper()
+	var input, expectedOut strings.Builder
+	for _, line := range strings.Split(strings.TrimPrefix(transcript, "\n"), "\n") {
+		if prompt, typed, ok := cutPrompt(line); ok {
+			input.WriteString(typed + "\n")
+			expectedOut.WriteString(prompt)
+		} else if line != "" {
+			expectedOut.WriteString(line + "\n")
+		}
+	}
+	expectedOut.WriteString(PROMPT)
+
+	var out, errOut bytes.Buffer
+	Start(strings.NewReader(input.String()), &out, &errOut)
+	if out.String() != expectedOut.String() {
+		t.Errorf(util.RedText(fmt.Sprintf("%s: wrong output.\nexpected=%q\ngot=     %q", name, expectedOut.String(), out.String())))
+	}
+	if errOut.String() != expectedErr {
+		t.Errorf(util.RedText(fmt.Sprintf("%s: wrong errors.\nexpected=%q\ngot=     %q", name, expectedErr, errOut.String())))
+	}
+}
+
+func cutPrompt(line string) (string, string, bool) {
+	for _, prompt := range []string{PROMPT, CONTINUATION_PROMPT} {
+		if typed, ok := strings.CutPrefix(line, prompt); ok {
+			return prompt, typed, true
+		}
+	}
+	return "", "", false
+}
+
+func TestTranscripts(t *testing.T) {
+	testTranscript(t, "values", `
+>> 1 + 2 * 3
+7
+>> "a" + "b"
+ab
+>> let x = 1;
+>> if (x > 0) { true }
+true
+`, "")
+
+	testTranscript(t, "bindings persist", `
+>> let x = 5;
+>> let addX = fn(n) { n + x };
+>> addX(10)
+15
+>> _ * 2
+30
+`, "")
+
+	testTranscript(t, "multi-line input", `
+>> let max = fn(a, b) {
+..   if (a > b) {
+..     a
+..   } else {
+..     b
+..   }
+.. };
+>> max(3,
+.. 7)
+7
+>> "two
+.. lines"
+two
+lines
+`, "")
+
+	testTranscript(t, "an empty line submits incomplete input", `
+>> 1 +
+.. 
+>> 2
+2
+`, `error[P002]: no prefix parse function for EOF found
+ --> 3:1
+  |
+3 | 
+  | ^
+  = note: expected an expression, but "" cannot start one
+`)
+
+	testTranscript(t, "errors go to the error writer and keep the session", `
+>> let a = 1;
+>> a + true
+>> a + b
+>> let b = 2;
+>> a + b
+3
+`, `error[R001]: type mismatch: INTEGER + BOOLEAN
+ --> 1:1
+  |
+1 | a + true
+  | ^^^^^^^^
+error[R001]: identifier not found: b
+ --> 1:5
+  |
+1 | a + b
+  |     ^
+`)
+
+	testTranscript(t, "loops and constants across inputs", `
+>> const limit = 3;
+>> let total = 0;
+>> for (i in range(limit)) {
+..   total += i
+.. }
+>> total
+3
+>> limit = 4
+>> limit
+3
+`, `error[R001]: cannot assign to limit, it is a constant
+ --> 1:1
+  |
+1 | limit = 4
+  | ^^^^^
+`)
+
+	testTranscript(t, ":type leaves the session as it was", `
+>> let x = 1;
+>> let inc = fn() { x = x + 1 };
+>> let a = [1, 2];
+>> let h = {"k": 1};
+>> :type inc()
+INTEGER
+>> :type a[0] = 99
+INTEGER
+>> :type delete(h, "k")
+INTEGER
+>> :type x = "changed"
+STRING
+>> :type let y = 1;
+no value
+>> x
+1
+>> a
+[1, 2]
+>> h
+{"k": 1}
+>> :env
+_ = {"k": 1}
+a = [1, 2]
+h = {"k": 1}
+inc = fn() {
+(x = (x + 1))
+}
+x = 1
+`, "")
+
+	testTranscript(t, "commands", `
+>> :tokens
+showing tokens: on
+>> x
+1:1     IDENT     "x"
+>> :tokens
+showing tokens: off
+>> :ast
+showing the parsed program: on
+>> let y = -1 + 2;
+let y = ((-1) + 2);
+>> :ast
+showing the parsed program: off
+>> :type y
+INTEGER
+>> :env
+y = 1
+>> :reset
+session reset
+>> :env
+nothing is bound yet
+>> :what
+`, `error[R001]: identifier not found: x
+ --> 1:1
+  |
+1 | x
+  | ^
+unknown command :what, type :help to see every command
+`)
+}
@@ -0,0 +1,94 @@
+package repl
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ajtroup1/interpreters/evaluation/evaluator"
+	"github.com/ajtroup1/interpreters/evaluation/object"
+	"github.com/ajtroup1/interpreters/parsing/diagnostic"
+	"github.com/ajtroup1/interpreters/parsing/lexer"
+	"github.com/ajtroup1/interpreters/parsing/parser"
+	"github.com/ajtroup1/interpreters/parsing/token"
+)
+
+// The name the value of the last expression is bound to, so it can be used in the next input
+const LAST_VALUE = "_"
+
+/*
+A Session is everything the REPL remembers between inputs
+Every input is evaluated in the same environment, so bindings and functions from earlier inputs can be used by later ones
+An input that fails to parse or hits a runtime error doesn't throw the session away
+	Any bindings it made before failing are kept, just like they would be in a program
+*/
+type Session struct {
+	env        *object.Environment
+	showTokens bool // toggled by :tokens
+	showAST    bool // toggled by :ast
+}
+
+// Instantiates a Session with nothing bound yet
+func NewSession() *Session {
+	return &Session{env: object.NewEnvironment()}
+}
+
+// Forgets every binding, the session's settings are kept
+func (s *Session) Reset() {
+	s.env = object.NewEnvironment()
+}
+
+/*
+Parses and evaluates the source in the session's environment
+Returns the value to show for it, or nil if there is nothing to show, like after a let statement
+Parse errors and runtime errors are returned as diagnostics instead, with positions relative to the source
+*/
+func (s *Session) Eval(source string) (object.Object, []diagnostic.Diagnostic) {
+	result, diagnostics := evalIn(source, s.env)
+	if result != nil {
+		s.env.Set(LAST_VALUE, result)
+	}
+	return result, diagnostics
+}
+
+/*
+Evaluates the source like Eval, but against a copy of the session's environment and every value in it, and doesn't set _
+Nothing the source binds, assigns or changes in place is kept, even through a closure or an element of an array
+	Output it prints with puts still appears, since that has already left the session
+*/
+func (s *Session) Try(source string) (object.Object, []diagnostic.Diagnostic) {
+	return evalIn(source, s.env.Copy())
+}
+
+// Parses and evaluates the source in env, returning the value to show for it or the diagnostics explaining why it failed
+func evalIn(source string, env *object.Environment) (object.Object, []diagnostic.Diagnostic) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) != 0 {
+		return nil, diagnostics
+	}
+
+	result := evaluator.Eval(program, env)
+	if err, ok := result.(*object.Error); ok {
+		return nil, []diagnostic.Diagnostic{{Span: err.Span, Severity: diagnostic.Error, Code: diagnostic.RuntimeError, Message: err.Message}}
+	}
+	if result == nil || !program.EndsWithExpression() {
+		return nil, nil
+	}
+	return result, nil
+}
+
+/*
+Returns every keyword and binding in the session that starts with prefix, in alphabetical order
+Used for tab completion
+*/
+func (s *Session) Complete(prefix string) []string {
+	var matches []string
+	for _, word := range append(token.Keywords(), s.env.Names()...) {
+		if strings.HasPrefix(word, prefix) {
+			matches = append(matches, word)
+		}
+	}
+	sort.Strings(matches)
+	return slices.Compact(matches)
+}
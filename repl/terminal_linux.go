@@ -0,0 +1,9 @@
+package repl
+
+import "syscall"
+
+// The ioctl requests that read and change a terminal's settings, which Linux names differently from macOS and the BSDs
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -5,6 +5,10 @@ const (
 	Red     = "\033[31m"
 	Green   = "\033[32m"
 	Yellow  = "\033[33m"
+	Blue    = "\033[34m"
+	Magenta = "\033[35m"
+	Cyan    = "\033[36m"
+	Gray    = "\033[90m"
 )
 
 // Color functions to wrap text in specific color codes
@@ -18,4 +22,20 @@ func GreenText(text string) string {
 
 func YellowText(text string) string {
 	return Yellow + text + Reset
-}
\ No newline at end of file
+}
+
+func BlueText(text string) string {
+	return Blue + text + Reset
+}
+
+func MagentaText(text string) string {
+	return Magenta + text + Reset
+}
+
+func CyanText(text string) string {
+	return Cyan + text + Reset
+}
+
+func GrayText(text string) string {
+	return Gray + text + Reset
+}